@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/component/logs/export"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+func newCmdExport() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export BUNDLE LOGFILE",
+		Short: "Export lines of a log file from a bundle",
+		Long: `Export lines of a log file from a bundle as NDJSON, CSV or Parquet.
+
+Filters are given as field=value (equals), field!=value (not equals),
+field~value (includes), field!~value (excludes), field>value (greater
+than) or field<value (less than). Values are compared by the type of
+their field, such as numbers, dates and IP networks in CIDR notation.
+Dates may be relative to the bundle's collection time, such as
+@timestamp>now-15m. Lines must match every filter to be exported.`,
+		Args: cobra.ExactArgs(2),
+		RunE: doExport,
+	}
+
+	cmd.Flags().StringP("format", "f", export.FormatNDJSON, "export format (ndjson, csv, parquet)")
+	cmd.Flags().StringP("output", "o", "-", "output file, - for stdout")
+	cmd.Flags().StringArrayP("filter", "F", nil, "filter lines, may be repeated")
+	cmd.Flags().StringSliceP("columns", "C", nil, "columns to export for csv and parquet (defaults to the component's columns)")
+	cmd.Flags().StringP("parser", "p", "", "log parser (detected from the file by default)")
+
+	return cmd
+}
+
+func doExport(cmd *cobra.Command, args []string) error {
+	bundleFile, logFile := args[0], args[1]
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	filterExprs, _ := cmd.Flags().GetStringArray("filter")
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	parser, _ := cmd.Flags().GetString("parser")
+
+	textFilters := make([]*logs.TextFilter, 0, len(filterExprs))
+	for _, expr := range filterExprs {
+		f, err := logs.ParseTextFilter(expr)
+		if err != nil {
+			return err
+		}
+		textFilters = append(textFilters, f)
+	}
+
+	viewer, err := bundle.NewViewer(bundleFile)
+	if err != nil {
+		return err
+	}
+	defer viewer.Close()
+
+	file, err := viewer.OpenFile(logFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, detection, err := logs.Detect(file, logFile, parser)
+	if err != nil {
+		return err
+	}
+	p, err := logs.NewParser(detection.Parser)
+	if err != nil {
+		return err
+	}
+	logCtx, err := p.Parse(r)
+	if err != nil {
+		return fmt.Errorf("unable to parse %q: %w", logFile, err)
+	}
+	// Relative times in filters are anchored at the bundle's collection.
+	now := bundle.CollectionTime(viewer)
+	if now.IsZero() {
+		now = time.Now()
+	}
+	filters, err := logCtx.TypedFilters(now, textFilters...)
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		columns = logs.ResolveColumns(nil, logs.GetComponent(logFile), logCtx.Fields())
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := export.NewWriter(format, out, columns)
+	if err != nil {
+		return err
+	}
+	count, err := export.Export(w, logCtx, filters...)
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	logger.Debug().Str("bundle", bundleFile).Str("filename", logFile).Str("format", format).Int("lines", count).Msg("Exported log lines")
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/component/goroutine"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+func newCmdGoroutines() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "goroutines FILE | BUNDLE PATH",
+		Short: "Summarize a goroutine dump",
+		Long: `Summarize a goroutine dump, grouping goroutines with the same state
+and stack. The dump is read from FILE, - for stdin, or from PATH within
+a bundle.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: doGoroutines,
+	}
+
+	cmd.Flags().DurationP("min-wait", "w", 0, "only show groups blocked for at least this long")
+
+	return cmd
+}
+
+func doGoroutines(cmd *cobra.Command, args []string) error {
+	minWait, _ := cmd.Flags().GetDuration("min-wait")
+
+	r, closeFn, err := openDump(args)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	goroutines, err := goroutine.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	groups := goroutine.GroupByStack(goroutines)
+
+	_, _ = fmt.Fprintf(out, "%d goroutines in %d groups\n", len(goroutines), len(groups))
+	for _, g := range groups {
+		if g.MaxWait < minWait {
+			continue
+		}
+		printGroup(out, g)
+	}
+
+	return nil
+}
+
+func printGroup(out io.Writer, g goroutine.Group) {
+	header := fmt.Sprintf("\n%d goroutines [%s", len(g.Goroutines), g.State)
+	if g.MaxWait > 0 {
+		header += fmt.Sprintf(", up to %s", g.MaxWait.Round(time.Minute))
+	}
+	header += "]"
+	if g.IsLongBlocked() {
+		header += " (long blocked)"
+	}
+	_, _ = fmt.Fprintln(out, header)
+
+	ids := make([]string, 0, len(g.Goroutines))
+	for _, v := range g.Goroutines {
+		ids = append(ids, fmt.Sprint(v.ID))
+	}
+	_, _ = fmt.Fprintf(out, "IDs: %s\n", strings.Join(ids, ", "))
+
+	stack, err := logger.FormatStack(g.Lines())
+	if err != nil {
+		_, _ = fmt.Fprintln(out, strings.Join(g.Lines(), "\n"))
+		return
+	}
+	_, _ = out.Write(stack)
+}
+
+func openDump(args []string) (io.Reader, func(), error) {
+	if len(args) == 2 {
+		viewer, err := bundle.NewViewer(args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := viewer.OpenFile(args[1])
+		if err != nil {
+			_ = viewer.Close()
+			return nil, nil, err
+		}
+		return file, func() {
+			_ = file.Close()
+			_ = viewer.Close()
+		}, nil
+	}
+
+	if args[0] == "-" {
+		return os.Stdin, func() {}, nil
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, func() { _ = file.Close() }, nil
+}
@@ -36,6 +36,8 @@ func NewRootCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		newCmdRun(),
+		newCmdExport(),
+		newCmdGoroutines(),
 	)
 
 	cmd.PersistentFlags().StringP("log-level", "L", "info", "set log level (trace, debug, info, warn, error)")
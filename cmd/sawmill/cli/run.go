@@ -9,7 +9,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-	
+
 	"github.com/taylor-swanson/sawmill/internal/api"
 	"github.com/taylor-swanson/sawmill/internal/logger"
 )
@@ -25,6 +25,10 @@ func newCmdRun() *cobra.Command {
 	cmd.Flags().BoolP("https", "s", false, "use https")
 	cmd.Flags().StringP("cert", "c", "cert.pem", "path to server certificate file")
 	cmd.Flags().StringP("key", "k", "key.pem", "path to server key file")
+	cmd.Flags().StringP("rules-dir", "r", "", "directory containing additional diagnostics rules")
+	cmd.Flags().Int("parse-workers", api.DefaultOptions().ParseWorkers, "maximum number of log files parsed at the same time")
+	cmd.Flags().Int64("log-cache-size", api.DefaultOptions().LogCacheSize/(1024*1024), "maximum memory in MB used by parsed log files of each bundle")
+	cmd.Flags().Int64("max-upload-size", api.DefaultOptions().MaxUploadSize/(1024*1024), "maximum size in MB of an uploaded bundle")
 
 	return cmd
 }
@@ -35,7 +39,15 @@ func doRun(cmd *cobra.Command, _ []string) error {
 	key, _ := cmd.Flags().GetString("key")
 	https, _ := cmd.Flags().GetBool("https")
 
-	handler, err := api.NewHandler()
+	opts := api.DefaultOptions()
+	opts.RulesDir, _ = cmd.Flags().GetString("rules-dir")
+	opts.ParseWorkers, _ = cmd.Flags().GetInt("parse-workers")
+	logCacheSize, _ := cmd.Flags().GetInt64("log-cache-size")
+	opts.LogCacheSize = logCacheSize * 1024 * 1024
+	maxUploadSize, _ := cmd.Flags().GetInt64("max-upload-size")
+	opts.MaxUploadSize = maxUploadSize * 1024 * 1024
+
+	handler, err := api.NewHandler(opts)
 	if err != nil {
 		return err
 	}
@@ -47,7 +59,7 @@ func doRun(cmd *cobra.Command, _ []string) error {
 	done := make(chan struct{})
 
 	go func() {
-		notify := make(chan os.Signal)
+		notify := make(chan os.Signal, 1)
 		signal.Notify(notify, os.Interrupt)
 		<-notify
 
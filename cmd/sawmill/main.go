@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	// Timezones for displaying logs must be available on any host.
+	_ "time/tzdata"
 
 	"github.com/taylor-swanson/sawmill/cmd/sawmill/cli"
 	"github.com/taylor-swanson/sawmill/internal/logger"
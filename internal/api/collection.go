@@ -0,0 +1,373 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/multierr"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/component/config"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/component/state"
+	"github.com/taylor-swanson/sawmill/internal/jobs"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+	"github.com/taylor-swanson/sawmill/internal/rules"
+	"github.com/taylor-swanson/sawmill/internal/session"
+)
+
+// bundleSummary is a row of the collection table.
+type bundleSummary struct {
+	Hash             string
+	OriginalFilename string
+	Info             bundle.Info
+	PolicyRevision   int
+	HasState         bool
+	Healthy          int
+	Degraded         int
+	Failed           int
+	Findings         int
+	TopSeverity      rules.Severity
+	// Pending is true while the rules are still being evaluated.
+	Pending bool
+}
+
+func (h *Handler) handlePostCollection(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil {
+		h.renderError(w, r, uploadError(err))
+		return
+	}
+
+	c := session.NewCollection(r.FormValue("name"))
+	if c.Name == "" {
+		c.Name = "Collection " + c.ID.String()[:8]
+	}
+
+	added, failures, err := h.addToCollection(r, c)
+	if added == 0 && err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	logger.Debug().Str("collection", c.ID.String()).Int("bundles", len(c.Hashes())).Msg("Creating new collection")
+
+	h.sessionCollectionsMu.Lock()
+	h.sessionCollections[c.ID.String()] = c
+	h.sessionCollectionsMu.Unlock()
+
+	h.renderCollectionDetail(w, r, c, failures)
+}
+
+func (h *Handler) handlePostCollectionUpload(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.getCollection(chi.URLParam(r, "id"))
+	if !ok {
+		h.renderError(w, r, collectionNotFound(chi.URLParam(r, "id")))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil {
+		h.renderError(w, r, uploadError(err))
+		return
+	}
+
+	added, failures, err := h.addToCollection(r, c)
+	if added == 0 && err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	h.renderCollectionDetail(w, r, c, failures)
+}
+
+func (h *Handler) handleGetCollection(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.getCollection(chi.URLParam(r, "id"))
+	if !ok {
+		h.renderError(w, r, collectionNotFound(chi.URLParam(r, "id")))
+		return
+	}
+
+	h.renderCollectionDetail(w, r, c, nil)
+}
+
+func (h *Handler) handleGetCollectionSearch(w http.ResponseWriter, r *http.Request) {
+	type FileHits struct {
+		Filename string
+		Hits     int
+		URL      string
+	}
+	type BundleHits struct {
+		Hash             string
+		OriginalFilename string
+		Hostname         string
+		Hits             int
+		Files            []FileHits
+	}
+	type SearchInfo struct {
+		Filters []*logs.TextFilter
+		Bundles []BundleHits
+		Total   int
+	}
+
+	c, ok := h.getCollection(chi.URLParam(r, "id"))
+	if !ok {
+		h.renderError(w, r, collectionNotFound(chi.URLParam(r, "id")))
+		return
+	}
+
+	var info SearchInfo
+	for _, expr := range strings.Split(r.FormValue("filter"), "\n") {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		f, err := logs.ParseTextFilter(expr)
+		if err != nil {
+			h.renderError(w, r, badRequest(err))
+			return
+		}
+		info.Filters = append(info.Filters, f)
+	}
+	if len(info.Filters) == 0 {
+		h.renderError(w, r, badRequest(errors.New("missing filter")))
+		return
+	}
+
+	logger.Debug().Str("collection", c.ID.String()).Int("filters", len(info.Filters)).Msg("Searching collection")
+
+	// Every log of the collection is searched, so they are parsed in the
+	// background, showing progress until they can be searched.
+	sessions := h.collectionSessions(c)
+	parsed, ok := h.awaitLogs(w, r, jobTargetCollectionSearch, *r.URL, sessions...)
+	if !ok {
+		return
+	}
+
+	filtersJSON, _ := json.Marshal(info.Filters)
+
+	for _, s := range sessions {
+		bh := BundleHits{
+			Hash:             s.Hash,
+			OriginalFilename: s.OriginalFilename,
+			Hostname:         s.Viewer.Info().Host.Hostname,
+		}
+		for _, log := range parsed[s.Hash] {
+			if log.Err != nil {
+				// Hits in the other logs are still useful.
+				PropsFromContext(r.Context()).AppendError(log.Err)
+				continue
+			}
+			entry, logCtx := log.Entry, log.Context
+			// Fields may have different types in each log.
+			filters, err := logCtx.TypedFilters(s.CollectionTime(), info.Filters...)
+			if err != nil {
+				PropsFromContext(r.Context()).AppendError(err)
+				continue
+			}
+			hits := len(logCtx.Filter(filters...))
+			if hits == 0 {
+				continue
+			}
+			bh.Files = append(bh.Files, FileHits{
+				Filename: entry.Filename,
+				Hits:     hits,
+				URL: "/inspect/log/" + s.Hash + "?" + url.Values{
+					"filename": {entry.Filename},
+					"filters":  {string(filtersJSON)},
+				}.Encode(),
+			})
+			bh.Hits += hits
+		}
+		info.Total += bh.Hits
+		info.Bundles = append(info.Bundles, bh)
+	}
+
+	h.renderFragment(w, r, "collectionSearch", &info)
+}
+
+// renderCollectionDetail renders a collection with a summary of each bundle,
+// listing the uploaded files which couldn't be added to it.
+func (h *Handler) renderCollectionDetail(w http.ResponseWriter, r *http.Request, c *session.Collection, failures []uploadFailure) {
+	type CollectionInfo struct {
+		ID       string
+		Name     string
+		Bundles  []bundleSummary
+		Failures []uploadFailure
+	}
+
+	info := CollectionInfo{
+		ID:       c.ID.String(),
+		Name:     c.Name,
+		Failures: failures,
+	}
+	for _, s := range h.collectionSessions(c) {
+		summary, err := h.summarizeBundle(s)
+		if err != nil {
+			// The rest of the summary is still useful.
+			PropsFromContext(r.Context()).AppendError(err)
+		}
+		info.Bundles = append(info.Bundles, summary)
+	}
+
+	h.renderFragment(w, r, "collectionDetail", &info)
+}
+
+// uploadFailure is an uploaded file which couldn't be added to a collection.
+type uploadFailure struct {
+	Filename string
+	Message  string
+}
+
+// addToCollection opens a session for every uploaded bundle in the request and
+// adds them to the collection, returning the number of bundles added. Files
+// which can't be opened don't stop the others from being added, and are
+// returned as failures along with their combined errors.
+func (h *Handler) addToCollection(r *http.Request, c *session.Collection) (int, []uploadFailure, error) {
+	var (
+		added    int
+		failures []uploadFailure
+		errs     error
+	)
+	for _, header := range r.MultipartForm.File["file"] {
+		s, err := h.openUploadedFile(header)
+		if err != nil {
+			failures = append(failures, uploadFailure{
+				Filename: header.Filename,
+				Message:  errorMessage(err, errorStatus(err)),
+			})
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		c.Add(s.Hash)
+		added++
+	}
+	if added > 0 && errs != nil {
+		// The error isn't rendered, so it is recorded with the request.
+		PropsFromContext(r.Context()).AppendError(errs)
+	}
+
+	return added, failures, errs
+}
+
+// openUploadedFile returns the session for a bundle uploaded in a form.
+func (h *Handler) openUploadedFile(header *multipart.FileHeader) (*session.Session, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return h.openSession(file, header.Filename)
+}
+
+// summarizeBundle collects the details of a bundle shown in a collection.
+func (h *Handler) summarizeBundle(s *session.Session) (bundleSummary, error) {
+	summary := bundleSummary{
+		Hash:             s.Hash,
+		OriginalFilename: s.OriginalFilename,
+		Info:             s.Viewer.Info(),
+		PolicyRevision:   -1,
+	}
+
+	for _, v := range s.Viewer.GetConfigs() {
+		if v.Type != config.TypeAgentPolicy {
+			continue
+		}
+		// Fleet revisions start at 1, so 0 means the revision is missing.
+		if p, err := loadPolicy(s, v.Filename); err == nil && p.Revision > 0 {
+			summary.PolicyRevision = p.Revision
+		}
+		break
+	}
+
+	agentState, found, err := loadAgentState(s)
+	if err != nil {
+		return summary, err
+	}
+	summary.HasState = found
+	for _, c := range agentState.Components {
+		switch c.Worst() {
+		case state.StateFailed:
+			summary.Failed++
+		case state.StateDegraded:
+			summary.Degraded++
+		default:
+			summary.Healthy++
+		}
+	}
+
+	// The findings are filled in by the view once the rules are evaluated.
+	job := h.findingsJob(s)
+	select {
+	case <-job.Done():
+	default:
+		summary.Pending = true
+		return summary, nil
+	}
+
+	return summary, summarizeFindings(&summary, job)
+}
+
+// summarizeFindings adds the result of a findings job to a summary.
+func summarizeFindings(summary *bundleSummary, job *jobs.Job) error {
+	result, err := job.Result()
+	if err != nil {
+		return err
+	}
+	findings := result.(findingsResult)
+	summary.Findings = len(findings.Findings)
+	if len(findings.Findings) > 0 {
+		// Findings are sorted by severity, most severe first.
+		summary.TopSeverity = findings.Findings[0].Rule.Severity
+	}
+
+	return findings.Err
+}
+
+func (h *Handler) handleGetFindingsSummary(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+
+	logger.Debug().Str("hash", fileHash).Msg("Requesting a findings summary")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	summary := bundleSummary{Hash: s.Hash}
+	job := h.findingsJob(s)
+	if !awaitJob(job) {
+		summary.Pending = true
+	} else if err := summarizeFindings(&summary, job); err != nil {
+		PropsFromContext(r.Context()).AppendError(err)
+	}
+
+	h.renderFragment(w, r, "findingsSummary", &summary)
+}
+
+// collectionSessions returns the sessions of a collection which still exist.
+func (h *Handler) collectionSessions(c *session.Collection) []*session.Session {
+	var sessions []*session.Session
+	for _, fileHash := range c.Hashes() {
+		if s, ok := h.getSession(fileHash); ok {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions
+}
+
+func (h *Handler) getCollection(id string) (*session.Collection, bool) {
+	h.sessionCollectionsMu.RLock()
+	defer h.sessionCollectionsMu.RUnlock()
+
+	c, ok := h.sessionCollections[id]
+
+	return c, ok
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePostCollection_TooLarge(t *testing.T) {
+	h := newTestHandler(t)
+	data := makeTestBundle(t, "web-01")
+
+	rec := serve(h, newUploadRequest(t, http.MethodPost, "/collection", nil, data))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, h.sessionCollections, 1)
+	var id string
+	for id = range h.sessionCollections {
+	}
+
+	tooLarge := make([]byte, 2*1024*1024)
+	tests := map[string]string{
+		"create": "/collection",
+		"upload": "/collection/" + id + "/upload",
+	}
+
+	for name, target := range tests {
+		target := target
+		t.Run(name, func(t *testing.T) {
+			rec := serve(h, newUploadRequest(t, http.MethodPost, target, nil, tooLarge))
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+		})
+	}
+	require.Len(t, h.sessionCollections, 1)
+}
+
+func TestHandlePostCollection_PartialFailure(t *testing.T) {
+	h := newTestHandler(t)
+	web := makeTestBundle(t, "web-01")
+	db := makeTestBundle(t, "db-01")
+
+	// The bundles which could be opened are kept, and the others are listed.
+	rec := serve(h, newUploadRequest(t, http.MethodPost, "/collection", nil, web, []byte("not a zip")))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "<td>web-01</td>")
+	require.Contains(t, rec.Body.String(), "bundle-1.zip: &#34;bundle-1.zip&#34; is not a supported diagnostic bundle")
+	require.Len(t, h.sessionCollections, 1)
+	var id string
+	for id = range h.sessionCollections {
+	}
+
+	rec = serve(h, newUploadRequest(t, http.MethodPost, "/collection/"+id+"/upload", nil, []byte("not a zip"), db))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "<td>web-01</td>")
+	require.Contains(t, rec.Body.String(), "<td>db-01</td>")
+	require.Contains(t, rec.Body.String(), "bundle-0.zip: &#34;bundle-0.zip&#34; is not a supported diagnostic bundle")
+	require.Len(t, h.sessionCollections[id].Hashes(), 2)
+
+	// A collection isn't created if no bundle could be opened.
+	rec = serve(h, newUploadRequest(t, http.MethodPost, "/collection", nil, []byte("not a zip")))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Len(t, h.sessionCollections, 1)
+}
+
+func TestHandleGetCollectionSearch(t *testing.T) {
+	h := newTestHandler(t)
+
+	web := makeTestBundle(t, "web-01",
+		`{"@timestamp":"2023-01-04T10:00:00Z","log.level":"error","message":"a","count":10}`,
+		`{"@timestamp":"2023-01-04T10:01:00Z","log.level":"error","message":"b","count":2}`,
+		`{"@timestamp":"2023-01-04T10:02:00Z","log.level":"info","message":"c","count":20}`,
+	)
+	db := makeTestBundle(t, "db-01",
+		`{"@timestamp":"2023-01-04T10:00:00Z","log.level":"error","message":"d","count":7}`,
+		`{"@timestamp":"2023-01-04T10:01:00Z","log.level":"error","message":"e","count":50}`,
+		`{"@timestamp":"2023-01-04T10:02:00Z","log.level":"warn","message":"f","count":9}`,
+	)
+
+	rec := serve(h, newUploadRequest(t, http.MethodPost, "/collection", map[string]string{"name": "test"}, web, db))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, h.sessionCollections, 1)
+	var id string
+	for id = range h.sessionCollections {
+	}
+
+	tests := map[string]struct {
+		filter string
+		want   map[string]int
+		total  int
+	}{
+		"string": {
+			filter: "log.level=error",
+			want:   map[string]int{"web-01": 2, "db-01": 2},
+			total:  4,
+		},
+		// Counts are compared as numbers, so 10 is greater than 5.
+		"typed": {
+			filter: "log.level=error\ncount>5",
+			want:   map[string]int{"web-01": 1, "db-01": 2},
+			total:  3,
+		},
+		"no_match": {
+			filter: "log.level=debug",
+			want:   map[string]int{"web-01": 0, "db-01": 0},
+			total:  0,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			target := "/collection/" + id + "/search?" + url.Values{"filter": {tc.filter}}.Encode()
+			rec := serve(h, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			body := rec.Body.String()
+
+			require.Contains(t, body, "<b>"+strconv.Itoa(tc.total)+"</b> matching lines across 2 bundles")
+			for hostname, hits := range tc.want {
+				require.Regexp(t, `<td>`+hostname+`</td>\s*<td>`+strconv.Itoa(hits)+`</td>`, body)
+			}
+
+			// Each file links to its log, filtered by the search.
+			links := regexp.MustCompile(`hx-get="([^"]+)"`).FindAllStringSubmatch(body, -1)
+			hashes := map[string]bool{}
+			for _, link := range links {
+				u, err := url.Parse(html.UnescapeString(link[1]))
+				require.NoError(t, err)
+				require.Equal(t, testBundleLog, u.Query().Get("filename"))
+				require.NotEmpty(t, u.Query().Get("filters"))
+				hashes[u.Path] = true
+
+				// The link shows the same lines.
+				rec := serve(h, httptest.NewRequest(http.MethodGet, u.String(), nil))
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			}
+			want := map[string]bool{}
+			if tc.total > 0 {
+				want["/inspect/log/"+bundleHash(web)] = true
+				want["/inspect/log/"+bundleHash(db)] = true
+			}
+			require.Equal(t, want, hashes)
+		})
+	}
+}
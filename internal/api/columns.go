@@ -0,0 +1,112 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// columnType defines how values of a table column are formatted.
+type columnType int
+
+const (
+	columnTypeString columnType = iota
+	columnTypeNumber
+	columnTypeTime
+)
+
+// tableColumn is a column shown in a log table.
+type tableColumn struct {
+	Field string
+	Type  columnType
+	// Location is the timezone time columns are displayed in. If nil, times
+	// keep the offset they were logged with.
+	Location *time.Location
+}
+
+// Key returns the key used for the column in the table data. Dots are not
+// allowed, as the table would treat them as nested fields.
+func (c tableColumn) Key() string {
+	return strings.ReplaceAll(c.Field, ".", "_")
+}
+
+// Format formats a flattened field value for display in this column.
+func (c tableColumn) Format(value any) any {
+	switch c.Type {
+	case columnTypeTime:
+		if str, ok := value.(string); ok {
+			if t, ok := logs.ParseDate(str); ok {
+				return displayTime(t, c.Location)
+			}
+			return str
+		}
+	case columnTypeNumber:
+		if num, ok := value.(float64); ok {
+			return num
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return jsonString(v)
+	}
+}
+
+// tableTimeFormat is the layout used to display timestamps in a table.
+const tableTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// displayTime formats a timestamp for display in loc. If loc is nil, the time
+// keeps the offset it was logged with.
+func displayTime(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	return t.Format(tableTimeFormat)
+}
+
+// makeColumns builds typed table columns for fields, using entries to infer
+// the type of each column. Time columns are displayed in loc.
+func makeColumns(fields []string, entries []collections.Fields, loc *time.Location) []tableColumn {
+	columns := make([]tableColumn, 0, len(fields))
+
+	for _, field := range fields {
+		if field == "id" {
+			continue
+		}
+		col := tableColumn{Field: field, Location: loc}
+		if field == logs.TimestampField {
+			col.Type = columnTypeTime
+		} else if isNumberField(field, entries) {
+			col.Type = columnTypeNumber
+		}
+		columns = append(columns, col)
+	}
+
+	return columns
+}
+
+// isNumberField returns true if every value of field in entries is a number,
+// and at least one value is present.
+func isNumberField(field string, entries []collections.Fields) bool {
+	found := false
+
+	for _, entry := range entries {
+		v, ok := entry.Get(field)
+		if !ok {
+			continue
+		}
+		if _, ok = v.(float64); !ok {
+			return false
+		}
+		found = true
+	}
+
+	return found
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle/subset"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/upload"
+)
+
+// Kinds of errors, which pick the status code of the response to a failed
+// request.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrBadRequest      = errors.New("bad request")
+	ErrBadBundle       = errors.New("bad bundle")
+	ErrUnsupportedFile = errors.New("unsupported file")
+	ErrTooLarge        = errors.New("too large")
+)
+
+// kindError gives an error one of the kinds above without changing its
+// message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// badRequest marks err as caused by invalid request parameters.
+func badRequest(err error) error {
+	return &kindError{kind: ErrBadRequest, err: err}
+}
+
+// badBundle marks err as caused by an uploaded file which is not a bundle.
+func badBundle(err error) error {
+	return &kindError{kind: ErrBadBundle, err: err}
+}
+
+// unsupportedFile marks err as caused by a file of the bundle which can't be
+// read as the requested type.
+func unsupportedFile(err error) error {
+	return &kindError{kind: ErrUnsupportedFile, err: err}
+}
+
+// uploadError classifies an error reading an uploaded form. Bodies over the
+// upload limit are too large, and other errors are bad requests.
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &kindError{kind: ErrTooLarge, err: err}
+	}
+
+	return badRequest(err)
+}
+
+// bundleNotFound returns the error for a bundle which is not open.
+func bundleNotFound(hash string) error {
+	return fmt.Errorf("bundle %q %w", hash, ErrNotFound)
+}
+
+// collectionNotFound returns the error for a collection which doesn't exist.
+func collectionNotFound(id string) error {
+	return fmt.Errorf("collection %q %w", id, ErrNotFound)
+}
+
+// errorStatus returns the HTTP status code of the response to a request which
+// failed with err.
+func errorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, fs.ErrNotExist), errors.Is(err, upload.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBadRequest), errors.Is(err, logs.ErrParserUnsupported), errors.Is(err, upload.ErrEmpty):
+		return http.StatusBadRequest
+	case errors.Is(err, upload.ErrOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, upload.ErrLocked):
+		return http.StatusLocked
+	case errors.Is(err, ErrBadBundle), errors.Is(err, zip.ErrFormat), errors.Is(err, subset.ErrRedact):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnsupportedFile), errors.Is(err, logs.ErrFileTypeUnsupported):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrTooLarge), errors.Is(err, upload.ErrTooLarge), errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errorMessage returns the description of err given to the client. Internal
+// errors are only described in the server's log.
+func errorMessage(err error, status int) string {
+	if status >= http.StatusInternalServerError {
+		return "An unexpected error occurred."
+	}
+
+	return err.Error()
+}
+
+// renderError records err with the request and renders it for the UI. Requests
+// made by htmx receive a fragment, which replaces the requested content, and
+// other requests a full page. Errors of the JSON API are written as problem
+// details instead.
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	type ErrorInfo struct {
+		Status    int
+		Title     string
+		Message   string
+		RequestID string
+	}
+
+	if strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	props := PropsFromContext(r.Context())
+	props.AppendError(err)
+
+	status := errorStatus(err)
+	info := ErrorInfo{
+		Status:    status,
+		Title:     http.StatusText(status),
+		Message:   errorMessage(err, status),
+		RequestID: props.RequestID(),
+	}
+
+	buf := bytes.NewBuffer(nil)
+	var tmplErr error
+	if r.Header.Get("HX-Request") == "true" {
+		tmplErr = h.fragments.ExecuteTemplate(buf, "error", &info)
+	} else {
+		tmplErr = h.errorTmpl.ExecuteTemplate(buf, "base", &info)
+	}
+	if tmplErr != nil {
+		props.AppendError(tmplErr)
+		http.Error(w, info.Message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// renderFragment renders a template of the fragments, or an error if it fails.
+// Nothing is written until the template has rendered, so a failed template
+// doesn't leave a partial response.
+func (h *Handler) renderFragment(w http.ResponseWriter, r *http.Request, name string, data any) {
+	buf := bytes.NewBuffer(nil)
+	if err := h.fragments.ExecuteTemplate(buf, name, data); err != nil {
+		h.renderError(w, r, fmt.Errorf("unable to render %q: %w", name, err))
+		return
+	}
+
+	_, _ = buf.WriteTo(w)
+}
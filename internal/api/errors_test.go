@@ -0,0 +1,167 @@
+package api
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle/subset"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/upload"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"not_found":             {err: bundleNotFound("abc"), want: http.StatusNotFound},
+		"file_not_found":        {err: fmt.Errorf("open: %w", fs.ErrNotExist), want: http.StatusNotFound},
+		"upload_not_found":      {err: upload.ErrNotFound, want: http.StatusNotFound},
+		"bad_request":           {err: badRequest(errors.New("invalid")), want: http.StatusBadRequest},
+		"parser_unsupported":    {err: logs.ErrParserUnsupported, want: http.StatusBadRequest},
+		"upload_empty":          {err: upload.ErrEmpty, want: http.StatusBadRequest},
+		"offset_mismatch":       {err: upload.ErrOffsetMismatch, want: http.StatusConflict},
+		"locked":                {err: upload.ErrLocked, want: http.StatusLocked},
+		"bad_bundle":            {err: badBundle(errors.New("not a bundle")), want: http.StatusUnprocessableEntity},
+		"zip_format":            {err: zip.ErrFormat, want: http.StatusUnprocessableEntity},
+		"redact":                {err: fmt.Errorf("copy: %w", subset.ErrRedact), want: http.StatusUnprocessableEntity},
+		"unsupported_file":      {err: unsupportedFile(errors.New("not a profile")), want: http.StatusUnsupportedMediaType},
+		"file_type_unsupported": {err: logs.ErrFileTypeUnsupported, want: http.StatusUnsupportedMediaType},
+		"too_large":             {err: uploadError(&http.MaxBytesError{Limit: 1}), want: http.StatusRequestEntityTooLarge},
+		"max_bytes":             {err: &http.MaxBytesError{Limit: 1}, want: http.StatusRequestEntityTooLarge},
+		"upload_too_large":      {err: upload.ErrTooLarge, want: http.StatusRequestEntityTooLarge},
+		"other":                 {err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, errorStatus(tc.err))
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := errors.New("secret detail")
+
+	require.Equal(t, "secret detail", errorMessage(err, http.StatusBadRequest))
+	require.NotContains(t, errorMessage(err, http.StatusInternalServerError), "secret detail")
+}
+
+func TestRenderError(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := map[string]struct {
+		target          string
+		htmx            bool
+		wantContentType string
+		wantPage        bool
+	}{
+		"fragment": {
+			target:          "/inspect/bundle/unknown",
+			htmx:            true,
+			wantContentType: "text/html; charset=utf-8",
+		},
+		"page": {
+			target:          "/inspect/bundle/unknown",
+			wantContentType: "text/html; charset=utf-8",
+			wantPage:        true,
+		},
+		"api": {
+			target:          "/api/v1/bundles/unknown",
+			wantContentType: "application/problem+json",
+		},
+		"api_htmx": {
+			target:          "/api/v1/bundles/unknown",
+			htmx:            true,
+			wantContentType: "application/problem+json",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			rec := serve(h, req)
+
+			require.Equal(t, http.StatusNotFound, rec.Code)
+			require.Equal(t, tc.wantContentType, rec.Header().Get("Content-Type"))
+			requestID := rec.Header().Get(requestIDHeader)
+			require.NotEmpty(t, requestID)
+
+			body := rec.Body.String()
+			if strings.HasPrefix(tc.target, apiPrefix) {
+				var problem apiProblem
+				decodeJSON(t, rec, tc.wantContentType, &problem)
+				require.Equal(t, apiProblem{
+					Type:      "about:blank",
+					Title:     "Not Found",
+					Status:    http.StatusNotFound,
+					Detail:    `bundle "unknown" not found`,
+					Instance:  tc.target,
+					RequestID: requestID,
+				}, problem)
+				return
+			}
+
+			require.Contains(t, body, `role="alert"`)
+			require.Contains(t, body, "404 Not Found:")
+			require.Contains(t, body, "Request ID: "+requestID)
+			if tc.wantPage {
+				require.Contains(t, body, "<html")
+			} else {
+				require.NotContains(t, body, "<html")
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := map[string]struct {
+		header   string
+		wantSame bool
+	}{
+		"valid":     {header: "abc-123_x.y", wantSame: true},
+		"missing":   {header: ""},
+		"too_long":  {header: strings.Repeat("a", 65)},
+		"invalid":   {header: "abc 123"},
+		"non_ascii": {header: "abcé"},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/bundles/unknown", nil)
+			if tc.header != "" {
+				req.Header.Set(requestIDHeader, tc.header)
+			}
+			rec := serve(h, req)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tc.wantSame {
+				require.Equal(t, tc.header, got)
+			} else {
+				_, err := uuid.Parse(got)
+				require.NoError(t, err, got)
+			}
+
+			var problem apiProblem
+			decodeJSON(t, rec, "application/problem+json", &problem)
+			require.Equal(t, got, problem.RequestID)
+		})
+	}
+}
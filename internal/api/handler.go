@@ -1,15 +1,23 @@
 package api
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"github.com/taylor-swanson/sawmill/internal/collections"
 	"html/template"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,12 +28,44 @@ import (
 	"go.uber.org/multierr"
 
 	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/bundle/subset"
 	"github.com/taylor-swanson/sawmill/internal/component/config"
+	"github.com/taylor-swanson/sawmill/internal/component/goroutine"
 	"github.com/taylor-swanson/sawmill/internal/component/logs"
-	"github.com/taylor-swanson/sawmill/internal/hash"
+	"github.com/taylor-swanson/sawmill/internal/component/logs/export"
+	"github.com/taylor-swanson/sawmill/internal/component/metrics"
+	"github.com/taylor-swanson/sawmill/internal/component/policy"
+	"github.com/taylor-swanson/sawmill/internal/component/profile"
+	"github.com/taylor-swanson/sawmill/internal/component/state"
+	"github.com/taylor-swanson/sawmill/internal/jobs"
 	"github.com/taylor-swanson/sawmill/internal/logger"
+	"github.com/taylor-swanson/sawmill/internal/rules"
 	"github.com/taylor-swanson/sawmill/internal/session"
 	"github.com/taylor-swanson/sawmill/internal/ui"
+	"github.com/taylor-swanson/sawmill/internal/upload"
+)
+
+const (
+	// defaultContextLines is the number of lines shown before and after a line
+	// when viewing its surrounding context.
+	defaultContextLines = 10
+	// defaultTimelineWindow is the time window around a line used when viewing
+	// lines from all log files.
+	defaultTimelineWindow = 5 * time.Second
+	// defaultProfileTop is the number of functions listed when viewing a
+	// profile.
+	defaultProfileTop = 30
+	// goroutineDumpSniffSize is the number of bytes read from the start of a
+	// file to check whether it is a goroutine dump.
+	goroutineDumpSniffSize = 4096
+	// defaultJobWait is how long a request waits for a parsing job before
+	// showing its progress instead.
+	defaultJobWait = 500 * time.Millisecond
+	// bundleFilePattern names the temporary files holding uploaded bundles.
+	bundleFilePattern = "sawmill-*.zip"
+	// maxFormMemory is the most memory used for the files of an uploaded form.
+	// The rest is written to temporary files.
+	maxFormMemory = 32 << 20 // 32 MB
 )
 
 // contextKey defines keys for context values.
@@ -36,9 +76,19 @@ const (
 	ctxProps contextKey = iota + 1
 )
 
+// requestIDHeader is the header carrying the ID of a request.
+const requestIDHeader = "X-Request-Id"
+
 // CtxProps defines properties for a context, such as tracking errors.
 type CtxProps struct {
-	errs error
+	requestID string
+	errs      error
+}
+
+// RequestID returns the ID of the request, which is logged with it and returned
+// in the X-Request-Id header.
+func (c *CtxProps) RequestID() string {
+	return c.requestID
 }
 
 // AppendError appends an error to CtxProps.
@@ -53,28 +103,87 @@ func PropsFromContext(ctx context.Context) *CtxProps {
 	return props
 }
 
+// Options configures a Handler.
+type Options struct {
+	// RulesDir is a directory containing additional diagnostics rules. If empty,
+	// only the built-in rules are used.
+	RulesDir string
+	// ParseWorkers is the maximum number of log files parsed at the same time.
+	ParseWorkers int
+	// LogCacheSize is the maximum estimated memory, in bytes, used by the parsed
+	// log files cached for each bundle. It is not a global bound: memory grows
+	// with the number of open bundles.
+	LogCacheSize int64
+	// MaxUploadSize is the largest bundle, in bytes, which can be uploaded.
+	// Uploads of several bundles to a collection are limited as a whole.
+	MaxUploadSize int64
+}
+
+// DefaultOptions returns the default Handler options.
+func DefaultOptions() Options {
+	return Options{
+		ParseWorkers:  runtime.NumCPU(),
+		LogCacheSize:  session.DefaultLogCacheSize,
+		MaxUploadSize: 100 * 1024 * 1024, // 100 MB
+	}
+}
+
 type Handler struct {
 	// Embedding chi.Mux.
 	*chi.Mux
 
 	indexTmpl *template.Template
+	errorTmpl *template.Template
 	fragments *template.Template
 
 	maxUploadSize int64
+	logCacheSize  int64
+
+	uploads *upload.Manager
+
+	rules []*rules.Rule
+
+	jobs *jobs.Manager
 
 	sessions   map[string]*session.Session
 	sessionsMu sync.RWMutex
+
+	sessionCollections   map[string]*session.Collection
+	sessionCollectionsMu sync.RWMutex
 }
 
 // middlewareCtxProps injects a CtxProps instance into the request's context.
+// The request ID is taken from the request's X-Request-Id header if valid, and
+// generated otherwise.
 func (h *Handler) middlewareCtxProps(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(context.WithValue(r.Context(), ctxProps, &CtxProps{}))
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		r = r.WithContext(context.WithValue(r.Context(), ctxProps, &CtxProps{requestID: requestID}))
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// validRequestID returns true if id is a non-empty request ID of at most 64
+// letters, digits, dashes, dots and underscores.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
 // middlewareRecovery adds panic recovery to the request.
 func (h *Handler) middlewareRecovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -84,11 +193,13 @@ func (h *Handler) middlewareRecovery(next http.Handler) http.Handler {
 				logger.ErrorStacktrace(p)
 				logger.Error().
 					Str("panic", fmt.Sprintf("%v", p)).
+					Str("request_id", PropsFromContext(r.Context()).RequestID()).
 					Str("method", r.Method).
 					Str("url", r.URL.String()).
 					Str("client", r.RemoteAddr).
 					Dur("elapsed", time.Since(start)).
 					Msg("Request panic")
+				h.renderError(w, r, fmt.Errorf("panic: %v", p))
 			}
 		}()
 
@@ -105,395 +216,1771 @@ func (h *Handler) middlewareLogger(next http.Handler) http.Handler {
 		next.ServeHTTP(ww, r)
 
 		props := PropsFromContext(r.Context())
-		if props.errs != nil {
-			logger.Error().
-				Err(props.errs).
-				Str("method", r.Method).
-				Str("url", r.URL.String()).
-				Str("client", r.RemoteAddr).
-				Int("status", ww.Status()).
-				Int("bytes_written", ww.BytesWritten()).
-				Dur("elapsed", time.Since(start)).
-				Msg("Request")
-		} else {
-			logger.Debug().
-				Str("method", r.Method).
-				Str("url", r.URL.String()).
-				Str("client", r.RemoteAddr).
-				Int("status", ww.Status()).
-				Int("bytes_written", ww.BytesWritten()).
-				Dur("elapsed", time.Since(start)).
-				Msg("Request")
+		event := logger.Debug()
+		switch {
+		case props.errs == nil:
+		case ww.Status() >= http.StatusBadRequest && ww.Status() < http.StatusInternalServerError:
+			// Errors caused by the client are not a problem of the server.
+			event = logger.Warn().Err(props.errs)
+		default:
+			event = logger.Error().Err(props.errs)
 		}
+		event.
+			Str("request_id", props.requestID).
+			Str("method", r.Method).
+			Str("url", r.URL.String()).
+			Str("client", r.RemoteAddr).
+			Int("status", ww.Status()).
+			Int("bytes_written", ww.BytesWritten()).
+			Dur("elapsed", time.Since(start)).
+			Msg("Request")
 	})
 }
 
 func (h *Handler) handleGetRoot(w http.ResponseWriter, r *http.Request) {
-	if err := h.indexTmpl.ExecuteTemplate(w, "base", nil); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	buf := bytes.NewBuffer(nil)
+	if err := h.indexTmpl.ExecuteTemplate(buf, "base", nil); err != nil {
+		h.renderError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusTeapot)
+
+	_, _ = buf.WriteTo(w)
 }
 
 func (h *Handler) handlePostUpload(w http.ResponseWriter, r *http.Request) {
-	type SessionState struct {
-		Hash             string
-		Filename         string
-		OriginalFilename string
-		Info             bundle.Info
-		Configs          []config.Entry
-		Logs             []logs.Entry
-	}
-
-	if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil {
+		h.renderError(w, r, uploadError(err))
 		return
 	}
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		h.renderError(w, r, badRequest(fmt.Errorf("missing bundle file: %w", err)))
 		return
 	}
+	defer file.Close()
 
-	fileHash, err := hash.SHA256FromReader(file)
+	s, err := h.openSession(file, header.Filename)
 	if err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		h.renderError(w, r, err)
 		return
 	}
 
-	h.sessionsMu.RLock()
-	if s, ok := h.sessions[fileHash]; ok {
-		logger.Debug().Str("hash", s.Hash).Str("filename", s.Filename).Msg("Using existing session")
-		h.sessionsMu.RUnlock()
-
-		state := SessionState{
-			Hash:             s.Hash,
-			Filename:         s.Filename,
-			OriginalFilename: header.Filename,
-			Info:             s.Viewer.Info(),
-			Configs:          s.Viewer.GetConfigs(),
-			Logs:             s.Viewer.GetLogs(),
-		}
+	h.renderBundleDetail(w, r, s, header.Filename, "")
+}
 
-		if err = h.fragments.ExecuteTemplate(w, "bundleDetail", &state); err != nil {
-			// TODO: Add nicer error handling.
-			PropsFromContext(r.Context()).AppendError(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+func (h *Handler) handleGetInspectBundle(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+
+	logger.Debug().Str("hash", fileHash).Msg("Requesting a bundle")
 
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
 		return
 	}
-	h.sessionsMu.RUnlock()
 
-	// Reset reader back to beginning of file.
-	_, _ = file.Seek(0, 0)
+	h.renderBundleDetail(w, r, s, s.OriginalFilename, r.FormValue("collection"))
+}
+
+// renderBundleDetail renders the detail view of a session's bundle. If the
+// bundle was opened from a collection, its ID is given to link back to it.
+func (h *Handler) renderBundleDetail(w http.ResponseWriter, r *http.Request, s *session.Session, originalFilename string, collectionID string) {
+	type SessionState struct {
+		Hash             string
+		Filename         string
+		OriginalFilename string
+		Collection       string
+		Info             bundle.Info
+		Configs          []config.Entry
+		Logs             []logs.Entry
+		Rotated          []logs.RotatedLog
+	}
+
+	state := SessionState{
+		Hash:             s.Hash,
+		Filename:         s.Filename,
+		OriginalFilename: originalFilename,
+		Collection:       collectionID,
+		Info:             s.Viewer.Info(),
+		Configs:          s.Viewer.GetConfigs(),
+		Logs:             s.Viewer.GetLogs(),
+		Rotated:          logs.GroupRotated(s.Viewer.GetLogs()),
+	}
+
+	h.renderFragment(w, r, "bundleDetail", &state)
+}
 
-	tmpFile, err := os.CreateTemp("", "sawmill-*.zip")
+// openSession returns the session for an uploaded bundle, creating one if the
+// bundle has not been seen before. The bundle is hashed while it is written to
+// its temporary file.
+func (h *Handler) openSession(file io.Reader, originalFilename string) (*session.Session, error) {
+	tmpFile, err := os.CreateTemp("", bundleFilePattern)
 	if err != nil {
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	logger.Debug().Str("path", tmpFile.Name()).Str("bundle_filename", header.Filename).Msg("Writing bundle to temporary file")
-	//defer func() {
-	//	if err := os.Remove(tmpFile.Name()); err != nil {
-	//		logger.Warn().Err(err).Str("path", tmpFile.Name()).Msg("Failed to remove bundle file")
-	//	}
-	//}()
-	if _, err = io.Copy(tmpFile, file); err != nil {
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	logger.Debug().Str("path", tmpFile.Name()).Str("bundle_filename", originalFilename).Msg("Writing bundle to temporary file")
+	hasher := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(tmpFile, hasher), file); err != nil {
+		_ = tmpFile.Close()
 		_ = os.Remove(tmpFile.Name())
-		return
+		return nil, err
+	}
+	if err = tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	return h.openSessionFile(tmpFile.Name(), originalFilename, fmt.Sprintf("%x", hasher.Sum(nil)))
+}
+
+// openSessionFile returns the session for a bundle written to filename, which
+// is owned by the session from then on. If a session was already opened for
+// the same bundle, the file is removed and that session is returned.
+func (h *Handler) openSessionFile(filename string, originalFilename string, fileHash string) (*session.Session, error) {
+	if s, ok := h.getSession(fileHash); ok {
+		logger.Debug().Str("hash", s.Hash).Str("filename", s.Filename).Msg("Using existing session")
+		_ = os.Remove(filename)
+		return s, nil
 	}
-	_ = tmpFile.Close()
 
-	viewer, err := bundle.NewViewer(tmpFile.Name())
+	viewer, err := bundle.NewViewer(filename)
 	if err != nil {
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = os.Remove(tmpFile.Name())
-		return
+		_ = os.Remove(filename)
+		logger.Debug().Err(err).Str("bundle_filename", originalFilename).Msg("Unable to open bundle")
+		return nil, badBundle(fmt.Errorf("%q is not a supported diagnostic bundle", originalFilename))
 	}
 
 	// Make a new session.
 	s := session.Session{
 		ID:               uuid.New(),
-		Filename:         tmpFile.Name(),
-		OriginalFilename: header.Filename,
+		Filename:         filename,
+		OriginalFilename: originalFilename,
 		Hash:             fileHash,
 		Viewer:           viewer,
-		LogContexts:      map[string]*logs.Context{},
+		Logs:             session.NewLogCache(h.logCacheSize),
 	}
-	logger.Debug().Str("hash", s.Hash).Str("filename", s.Filename).Msg("Creating new session")
 
 	h.sessionsMu.Lock()
-	h.sessions[s.Hash] = &s
-	h.sessionsMu.Unlock()
+	defer h.sessionsMu.Unlock()
 
-	state := SessionState{
-		Hash:             s.Hash,
-		Filename:         s.Filename,
-		OriginalFilename: s.OriginalFilename,
-		Info:             s.Viewer.Info(),
-		Configs:          s.Viewer.GetConfigs(),
-		Logs:             s.Viewer.GetLogs(),
+	// The same bundle may have been uploaded at the same time.
+	if existing, ok := h.sessions[fileHash]; ok {
+		_ = viewer.Close()
+		_ = os.Remove(filename)
+		return existing, nil
 	}
+	logger.Debug().Str("hash", s.Hash).Str("filename", s.Filename).Msg("Creating new session")
+	h.sessions[s.Hash] = &s
 
-	if err = h.fragments.ExecuteTemplate(w, "bundleDetail", &state); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	return &s, nil
 }
 
-func (h *Handler) handleGetInspectConfig(w http.ResponseWriter, r *http.Request) {
-	type ConfigInfo struct {
-		Filename string
-		Content  string
+func (h *Handler) handleGetInspectFindings(w http.ResponseWriter, r *http.Request) {
+	type FindingsInfo struct {
+		Hash     string
+		Findings []rules.Finding
 	}
 
 	fileHash := chi.URLParam(r, "hash")
-	filename := r.FormValue("filename")
 
-	logger.Debug().Str("hash", fileHash).Str("filename", filename).Msg("Requesting a config file")
+	logger.Debug().Str("hash", fileHash).Msg("Requesting findings")
 
-	h.sessionsMu.RLock()
-	s, ok := h.sessions[fileHash]
-	h.sessionsMu.RUnlock()
+	s, ok := h.getSession(fileHash)
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
+		h.renderError(w, r, bundleNotFound(fileHash))
 		return
 	}
 
-	file, err := s.Viewer.OpenFile(filename)
-	if err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	// Evaluating the rules parses every log in the bundle, so it runs in the
+	// background once per session, showing progress until it has finished.
+	job := h.findingsJob(s)
+	if !awaitJob(job) {
+		h.renderJob(w, r, job, jobTargetFindings, r.URL.RequestURI())
 		return
 	}
-
-	buf := bytes.NewBuffer(nil)
-	if _, err = io.Copy(buf, file); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	result, err := job.Result()
+	if err != nil {
+		h.renderError(w, r, err)
 		return
 	}
-
-	configInfo := ConfigInfo{
-		Filename: filename,
-		Content:  buf.String(),
+	findings := result.(findingsResult)
+	if findings.Err != nil {
+		// Findings for the files that could be read are still useful.
+		PropsFromContext(r.Context()).AppendError(findings.Err)
 	}
 
-	if err = h.fragments.ExecuteTemplate(w, "configDetail", &configInfo); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	info := FindingsInfo{
+		Hash:     fileHash,
+		Findings: findings.Findings,
 	}
+
+	h.renderFragment(w, r, "findings", &info)
 }
 
-func (h *Handler) handleGetInspectLog(w http.ResponseWriter, r *http.Request) {
-	type LogData struct {
-		Fields  []string
-		Entries []collections.Fields
+func (h *Handler) handleGetInspectState(w http.ResponseWriter, r *http.Request) {
+	type ComponentInfo struct {
+		state.Component
+		Units   []state.NamedUnit
+		Worst   state.State
+		LogsURL string
 	}
-	type LogInfo struct {
-		Filename  string
-		LogData   LogData
-		Type      logs.Type
-		Component logs.Component
+	type StateInfo struct {
+		Hash           string
+		Found          bool
+		Agent          state.AgentState
+		Components     []ComponentInfo
+		ComputedConfig string
 	}
 
 	fileHash := chi.URLParam(r, "hash")
-	filename := r.FormValue("filename")
 
-	logger.Debug().Str("hash", fileHash).Str("filename", filename).Msg("Requesting a log file")
+	logger.Debug().Str("hash", fileHash).Msg("Requesting agent state")
 
-	h.sessionsMu.RLock()
-	s, ok := h.sessions[fileHash]
-	h.sessionsMu.RUnlock()
+	s, ok := h.getSession(fileHash)
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
+		h.renderError(w, r, bundleNotFound(fileHash))
 		return
 	}
-	logCtx, ok := s.LogContexts[fileHash]
-	if !ok {
-		file, err := s.Viewer.OpenFile(filename)
-		if err != nil {
-			// TODO: Add nicer error handling.
-			PropsFromContext(r.Context()).AppendError(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		p, err := logs.NewParser("ndjson")
-		if err != nil {
-			// TODO: Add nicer error handling.
-			PropsFromContext(r.Context()).AppendError(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
 
-		logCtx, err = p.Parse(file)
-		if err != nil {
-			// TODO: Add nicer error handling.
-			PropsFromContext(r.Context()).AppendError(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	info := StateInfo{Hash: fileHash}
 
-		s.LogContexts[filename] = logCtx
+	var err error
+	if info.Agent, info.Found, err = loadAgentState(s); err != nil {
+		h.renderError(w, r, err)
+		return
 	}
 
-	configInfo := LogInfo{
-		Filename: filename,
-		LogData: LogData{
-			Fields:  logCtx.Fields(),
-			Entries: logCtx.ViewAll(),
-		},
-		Type:      logs.GetType(filename),
-		Component: logs.GetComponent(filename),
+	for _, v := range s.Viewer.GetConfigs() {
+		if v.Type == config.TypeComputed {
+			info.ComputedConfig = v.Filename
+			break
+		}
 	}
 
-	if err := h.fragments.ExecuteTemplate(w, "logDetail", &configInfo); err != nil {
-		// TODO: Add nicer error handling.
-		PropsFromContext(r.Context()).AppendError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	for _, c := range info.Agent.Components {
+		info.Components = append(info.Components, ComponentInfo{
+			Component: c,
+			Units:     c.SortedUnits(),
+			Worst:     c.Worst(),
+			LogsURL:   componentLogsURL(s, fileHash, c.ID),
+		})
 	}
+
+	h.renderFragment(w, r, "state", &info)
 }
 
-func (h *Handler) loadTemplates() error {
-	var err error
+func (h *Handler) handleGetInspectPolicy(w http.ResponseWriter, r *http.Request) {
+	type InputInfo struct {
+		policy.Input
+		LogsURL string
+	}
+	type PackageInfo struct {
+		Package policy.Package
+		Inputs  []InputInfo
+	}
+	type PolicyInfo struct {
+		Hash       string
+		Filename   string
+		Policy     policy.Policy
+		Packages   []PackageInfo
+		Namespaces []string
+	}
 
-	tmplFuncs := template.FuncMap{
-		"configTypeToStr":   func(t config.Type) string { return t.String() },
-		"logTypeToStr":      func(t logs.Type) string { return t.String() },
-		"logComponentToStr": func(c logs.Component) string { return c.String() },
-		"marshalJSON": func(v any) template.JS {
-			data, mErr := json.Marshal(v)
-			if mErr != nil {
-				panic(mErr)
-			}
-			return template.JS(data)
-		},
-		"makeTableData": func(entries []collections.Fields, fields []string) template.JS {
-			tableData := make([]map[string]any, 0, len(entries))
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
 
-			i := 1
-			for _, entry := range entries {
-				entryData := make(map[string]any, len(fields))
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Msg("Requesting a policy")
 
-				entryData["id"] = i
-				for _, field := range fields {
-					if field == "id" {
-						continue
-					}
-					if v, ok := entry.Get(field); ok {
-						entryData[strings.ReplaceAll(field, ".", "_")] = fmt.Sprintf("%v", v)
-					} else {
-						entryData[strings.ReplaceAll(field, ".", "_")] = ""
-					}
-				}
-				tableData = append(tableData, entryData)
-				i++
-			}
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
 
-			data, mErr := json.Marshal(tableData)
-			if mErr != nil {
-				panic(mErr)
-			}
-			return template.JS(data)
-		},
-		"makeTableColumns": func(fields []string) template.JS {
-			type tableColumnData struct {
-				Title              string         `json:"title"`
-				Field              string         `json:"field"`
-				HeaderFilter       string         `json:"headerFilter,omitempty"`
-				HeaderFilterParams map[string]any `json:"headerFilterParams,omitempty"`
-			}
+	p, err := loadPolicy(s, filename)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
 
-			tableColumns := make([]tableColumnData, 0, len(fields))
-			for _, field := range fields {
-				if field == "id" {
-					continue
-				}
-				tcd := tableColumnData{
-					Title: field,
-					Field: strings.ReplaceAll(field, ".", "_"),
-				}
-				if field == "log.level" {
-					tcd.HeaderFilter = "list"
-					tcd.HeaderFilterParams = map[string]any{
-						"valuesLookup": true,
-						"clearable":    true,
-					}
-				}
-				if field == "" {
+	info := PolicyInfo{
+		Hash:       fileHash,
+		Filename:   filename,
+		Policy:     p,
+		Namespaces: p.Namespaces(),
+	}
+	for _, group := range p.Packages() {
+		pkg := PackageInfo{Package: group.Package}
+		for _, input := range group.Inputs {
+			pkg.Inputs = append(pkg.Inputs, InputInfo{
+				Input:   input,
+				LogsURL: componentLogsURL(s, fileHash, input.ComponentID()),
+			})
+		}
+		info.Packages = append(info.Packages, pkg)
+	}
 
-				}
+	h.renderFragment(w, r, "policyDetail", &info)
+}
 
-				tableColumns = append(tableColumns, tcd)
-			}
+func (h *Handler) handleGetInspectProfile(w http.ResponseWriter, r *http.Request) {
+	type ProfileInfo struct {
+		Hash        string
+		Filename    string
+		Name        string
+		SampleTypes []string
+		Sample      string
+		Unit        string
+		Total       int64
+		Top         []profile.Function
+		Flame       *profile.FlameNode
+		Goroutines  []profile.GoroutineGroup
+	}
 
-			data, mErr := json.Marshal(tableColumns)
-			if mErr != nil {
-				panic(mErr)
-			}
-			return template.JS(data)
-		},
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Msg("Requesting a profile")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
 	}
 
-	h.indexTmpl, err = template.New("base").Funcs(tmplFuncs).ParseFS(ui.FS, "templates/layouts/*.gohtml", "templates/index.gohtml")
+	file, err := s.Viewer.OpenFile(filename)
 	if err != nil {
-		return fmt.Errorf("unable to parse template: %w", err)
+		h.renderError(w, r, err)
+		return
 	}
-	h.fragments, err = template.New("bundleDetails").Funcs(tmplFuncs).ParseFS(ui.FS, "templates/fragments/*.gohtml")
+	defer file.Close()
+
+	p, err := profile.Parse(file)
 	if err != nil {
-		return fmt.Errorf("unable to parse template: %w", err)
+		h.renderError(w, r, unsupportedFile(fmt.Errorf("unable to parse profile %q: %w", filename, err)))
+		return
 	}
 
-	return nil
-}
-
-func (h *Handler) Close() {
-	h.sessionsMu.Lock()
-	defer h.sessionsMu.Unlock()
+	index, err := p.SampleIndex(r.FormValue("sample"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
 
-	for _, v := range h.sessions {
-		_ = v.Viewer.Close()
-		if err := os.Remove(v.Filename); err != nil {
-			logger.Error().Err(err).Str("filename", v.Filename).Msg("Failed to remove file")
-		} else {
-			logger.Debug().Str("filename", v.Filename).Msg("Removed file")
-		}
+	info := ProfileInfo{
+		Hash:        fileHash,
+		Filename:    filename,
+		Name:        bundle.ProfileName(filename),
+		SampleTypes: p.SampleTypes(),
+		Sample:      p.SampleTypes()[index],
+		Unit:        p.Unit(index),
+		Total:       p.Total(index),
+		Top:         p.Top(index, defaultProfileTop),
+		Flame:       p.Flame(index),
 	}
+	if p.IsGoroutine() {
+		info.Goroutines = p.Goroutines()
+	}
+
+	h.renderFragment(w, r, "profileDetail", &info)
 }
 
-func NewHandler() (*Handler, error) {
+func (h *Handler) handleGetInspectGoroutines(w http.ResponseWriter, r *http.Request) {
+	type DumpInfo struct {
+		Filename    string
+		Index       int
+		IsLog       bool
+		Count       int
+		LongBlocked int
+	}
+	type GoroutinesInfo struct {
+		Hash  string
+		Dumps []DumpInfo
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+
+	logger.Debug().Str("hash", fileHash).Msg("Requesting goroutine dumps")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	info := GoroutinesInfo{Hash: fileHash}
+	newDumpInfo := func(filename string, index int, isLog bool, goroutines []*goroutine.Goroutine) DumpInfo {
+		d := DumpInfo{Filename: filename, Index: index, IsLog: isLog, Count: len(goroutines)}
+		for _, g := range goroutines {
+			if g.IsLongBlocked() {
+				d.LongBlocked++
+			}
+		}
+		return d
+	}
+
+	// Dumps are also found in the logs, which are parsed in the background.
+	parsed, ok := h.awaitLogs(w, r, jobTargetGoroutines, *r.URL, s)
+	if !ok {
+		return
+	}
+
+	for _, filename := range findGoroutineDumpFiles(s.Viewer) {
+		goroutines, err := h.loadGoroutineDump(r.Context(), s, filename, -1)
+		if err != nil {
+			PropsFromContext(r.Context()).AppendError(err)
+			continue
+		}
+		info.Dumps = append(info.Dumps, newDumpInfo(filename, -1, false, goroutines))
+	}
+	for _, log := range parsed[s.Hash] {
+		if log.Err != nil {
+			// Dumps in the other logs are still useful.
+			PropsFromContext(r.Context()).AppendError(log.Err)
+			continue
+		}
+		for _, dump := range goroutine.FindInMessages(logMessages(log.Context)) {
+			info.Dumps = append(info.Dumps, newDumpInfo(log.Entry.Filename, dump.Index, true, dump.Goroutines))
+		}
+	}
+
+	h.renderFragment(w, r, "goroutines", &info)
+}
+
+func (h *Handler) handleGetInspectGoroutineDump(w http.ResponseWriter, r *http.Request) {
+	type DumpInfo struct {
+		Hash     string
+		Filename string
+		Index    int
+		Count    int
+		LongWait time.Duration
+		Groups   []goroutine.Group
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	index := -1
+	if value := r.FormValue("index"); value != "" {
+		var err error
+		if index, err = strconv.Atoi(value); err != nil {
+			h.renderError(w, r, badRequest(err))
+			return
+		}
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Int("index", index).Msg("Requesting a goroutine dump")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	goroutines, err := h.loadGoroutineDump(r.Context(), s, filename, index)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	if len(goroutines) == 0 {
+		h.renderError(w, r, fmt.Errorf("goroutine dump %d in %q %w", index, filename, ErrNotFound))
+		return
+	}
+
+	info := DumpInfo{
+		Hash:     fileHash,
+		Filename: filename,
+		Index:    index,
+		Count:    len(goroutines),
+		LongWait: goroutine.LongWait,
+		Groups:   goroutine.GroupByStack(goroutines),
+	}
+
+	h.renderFragment(w, r, "goroutineDump", &info)
+}
+
+func (h *Handler) handleGetInspectMetrics(w http.ResponseWriter, r *http.Request) {
+	type ChartSeries struct {
+		Label string
+		// Points are pairs of Unix milliseconds and values.
+		Points [][2]float64
+	}
+	type Chart struct {
+		Title  string
+		Series []ChartSeries
+	}
+	type SourceCharts struct {
+		Source string
+		Charts []Chart
+	}
+	type MetricsInfo struct {
+		Hash     string
+		Filename string
+		Metrics  []string
+		Selected map[string]bool
+		Sources  []SourceCharts
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+	selected := r.Form["metric"]
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Strs("metrics", selected).Msg("Requesting log metrics")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, r.FormValue("parser"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	series := metrics.Extract(logCtx)
+
+	info := MetricsInfo{
+		Hash:     fileHash,
+		Filename: filename,
+		Selected: map[string]bool{},
+	}
+	names := collections.NewSet[string]()
+	for _, v := range series {
+		names.Add(v.Metric)
+	}
+	info.Metrics = names.Values()
+	sort.Strings(info.Metrics)
+	for _, v := range selected {
+		info.Selected[v] = true
+	}
+
+	specs := metrics.DefaultCharts
+	if len(selected) > 0 {
+		specs = append([]metrics.ChartSpec{{Title: "Selected metrics", Metrics: selected}}, specs...)
+	}
+
+	for _, source := range metrics.Sources(series) {
+		sc := SourceCharts{Source: source}
+		for _, spec := range specs {
+			chart := Chart{Title: spec.Title}
+			for _, metric := range spec.Metrics {
+				found, ok := metrics.Find(series, source, metric)
+				if !ok {
+					continue
+				}
+				cs := ChartSeries{Label: metric, Points: make([][2]float64, 0, len(found.Points))}
+				for _, p := range found.Points {
+					cs.Points = append(cs.Points, [2]float64{float64(p.Time.UnixMilli()), p.Value})
+				}
+				chart.Series = append(chart.Series, cs)
+			}
+			if len(chart.Series) > 0 {
+				sc.Charts = append(sc.Charts, chart)
+			}
+		}
+		info.Sources = append(info.Sources, sc)
+	}
+
+	h.renderFragment(w, r, "metrics", &info)
+}
+
+func (h *Handler) handleGetInspectConfig(w http.ResponseWriter, r *http.Request) {
+	type ConfigInfo struct {
+		Filename string
+		Content  string
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Msg("Requesting a config file")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err = io.Copy(buf, file); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	configInfo := ConfigInfo{
+		Filename: filename,
+		Content:  buf.String(),
+	}
+
+	h.renderFragment(w, r, "configDetail", &configInfo)
+}
+
+func (h *Handler) handleGetInspectLog(w http.ResponseWriter, r *http.Request) {
+	type LogData struct {
+		Fields   []string
+		Selected map[string]bool
+		Columns  []tableColumn
+		Indices  []int
+		Entries  []collections.Fields
+	}
+	type LogInfo struct {
+		Hash      string
+		Filename  string
+		Filters   []*logs.TextFilter
+		LogData   LogData
+		Type      logs.Type
+		Component logs.Component
+		Detection logs.Detection
+		Parser    string
+		Parsers   []string
+		TZ        string
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+	parser := r.FormValue("parser")
+	tz := r.FormValue("tz")
+
+	filters, err := parseTextFilters(r.FormValue("filters"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+	loc, err := parseTimezone(tz)
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Int("filters", len(filters)).Msg("Requesting a log file")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	// Large files are parsed in the background, showing progress until the
+	// log can be viewed. The view is then reloaded with the job, whose result
+	// is used.
+	detection, err := detectLog(s, filename, parser)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	key := session.LogCacheKey{Filename: filename, Parser: detection.Parser}
+	logCtx, ok := s.Logs.Get(key)
+	if !ok {
+		job, ok := h.jobs.Get(r.FormValue("job"))
+		if !ok || job.Key() != logContextJobKey(s, key) {
+			job = h.startLogContextJob(s, key)
+		}
+		if !awaitJob(job) {
+			next := *r.URL
+			query := next.Query()
+			query.Set("job", job.ID)
+			next.RawQuery = query.Encode()
+			h.renderJob(w, r, job, jobTargetDetailView, next.RequestURI())
+			return
+		}
+		result, err := job.Result()
+		if err != nil {
+			h.renderError(w, r, err)
+			return
+		}
+		logCtx = result.(*logs.Context)
+	}
+
+	logFilters, err := logCtx.TypedFilters(s.CollectionTime(), filters...)
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+	indices := logCtx.Filter(logFilters...)
+	entries := logCtx.View(indices...)
+
+	component := logs.GetComponent(filename)
+	fields := logCtx.Fields()
+	chosen, _ := s.Columns(component)
+	columns := makeColumns(logs.ResolveColumns(chosen, component, fields), entries, loc)
+
+	selected := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		selected[col.Field] = true
+	}
+
+	configInfo := LogInfo{
+		Hash:     fileHash,
+		Filename: filename,
+		Filters:  filters,
+		LogData: LogData{
+			Fields:   fields,
+			Selected: selected,
+			Columns:  columns,
+			Indices:  indices,
+			Entries:  entries,
+		},
+		Type:      logs.GetType(filename),
+		Component: component,
+		Detection: detection,
+		Parser:    parser,
+		Parsers:   logs.Parsers(),
+		TZ:        tz,
+	}
+
+	h.renderFragment(w, r, "logDetail", &configInfo)
+}
+
+func (h *Handler) handlePostInspectLogColumns(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	component := logs.GetComponent(filename)
+	columns := r.PostForm["column"]
+	if r.PostFormValue("reset") != "" {
+		columns = nil
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("component", component.String()).Strs("columns", columns).Msg("Setting log columns")
+
+	s.SetColumns(component, columns)
+
+	h.handleGetInspectLog(w, r)
+}
+
+func (h *Handler) handleGetExportLog(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = export.FormatNDJSON
+	}
+
+	filters, err := parseTextFilters(r.FormValue("filters"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Str("format", format).Msg("Exporting a log file")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, r.FormValue("parser"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	columns := r.Form["column"]
+	if len(columns) == 0 {
+		component := logs.GetComponent(filename)
+		chosen, _ := s.Columns(component)
+		columns = logs.ResolveColumns(chosen, component, logCtx.Fields())
+	}
+
+	ew, err := export.NewWriter(format, w, columns)
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logFilters, err := logCtx.TypedFilters(s.CollectionTime(), filters...)
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	base := path.Base(logs.TrimCompressionExt(filename))
+	exportName := strings.TrimSuffix(base, path.Ext(base)) + "." + format
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": exportName}))
+
+	// Headers have been sent once writing starts, errors can only be logged.
+	if _, err = export.Export(ew, logCtx, logFilters...); err != nil {
+		PropsFromContext(r.Context()).AppendError(err)
+	}
+	if err = ew.Close(); err != nil {
+		PropsFromContext(r.Context()).AppendError(err)
+	}
+}
+
+func (h *Handler) handlePostExportBundle(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	opts := subset.Options{
+		Files:  r.PostForm["file"],
+		Redact: r.PostFormValue("redact") != "",
+	}
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	var err error
+	if opts.From, err = parseFormTime(r.PostFormValue("from"), s.CollectionTime()); err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+	if opts.To, err = parseFormTime(r.PostFormValue("to"), s.CollectionTime()); err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Strs("files", opts.Files).Bool("redact", opts.Redact).Msg("Exporting a sub-bundle")
+
+	// The sub-bundle is built before responding, so errors can still be shown
+	// instead of a truncated download.
+	tmpFile, err := os.CreateTemp("", bundleFilePattern)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if err = subset.Write(tmpFile, s.Viewer, opts); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	exportName := strings.TrimSuffix(s.OriginalFilename, path.Ext(s.OriginalFilename)) + "-subset.zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": exportName}))
+	http.ServeContent(w, r, exportName, time.Time{}, tmpFile)
+}
+
+func (h *Handler) handleGetInspectLogEntry(w http.ResponseWriter, r *http.Request) {
+	type LogEntryInfo struct {
+		Hash      string
+		Filename  string
+		Parser    string
+		TZ        string
+		Index     int
+		Timestamp string
+		JSON      string
+		Tree      []jsonNode
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		h.renderError(w, r, badRequest(fmt.Errorf("invalid index: %w", err)))
+		return
+	}
+
+	loc, err := parseTimezone(r.FormValue("tz"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Int("index", index).Msg("Requesting a log entry")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, r.FormValue("parser"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	entries := logCtx.View(index)
+	if len(entries) == 0 {
+		h.renderError(w, r, fmt.Errorf("line %d in %q %w", index, filename, ErrNotFound))
+		return
+	}
+
+	data, err := json.MarshalIndent(entries[0], "", "  ")
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	info := LogEntryInfo{
+		Hash:     fileHash,
+		Filename: filename,
+		Parser:   r.FormValue("parser"),
+		TZ:       r.FormValue("tz"),
+		Index:    index,
+		JSON:     string(data),
+		Tree:     makeJSONTree(entries[0], logCtx.FieldType),
+	}
+	if ts, ok := logCtx.Timestamp(index); ok {
+		info.Timestamp = displayTime(ts, loc)
+	}
+
+	h.renderFragment(w, r, "logEntry", &info)
+}
+
+func (h *Handler) handleGetInspectLogContext(w http.ResponseWriter, r *http.Request) {
+	type ContextLine struct {
+		Index     int
+		Target    bool
+		Timestamp string
+		Fields    collections.Fields
+	}
+	type LogContextInfo struct {
+		Hash     string
+		Filename string
+		TZ       string
+		Index    int
+		Lines    []ContextLine
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		h.renderError(w, r, badRequest(fmt.Errorf("invalid index: %w", err)))
+		return
+	}
+	count := defaultContextLines
+	if v := r.FormValue("lines"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count < 0 {
+			h.renderError(w, r, badRequest(fmt.Errorf("invalid lines: %q", v)))
+			return
+		}
+	}
+
+	loc, err := parseTimezone(r.FormValue("tz"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Int("index", index).Msg("Requesting log line context")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, r.FormValue("parser"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	if index < 0 || index >= logCtx.Lines() {
+		h.renderError(w, r, fmt.Errorf("line %d in %q %w", index, filename, ErrNotFound))
+		return
+	}
+
+	start, lines := logCtx.ViewSurrounding(index, count, count)
+
+	info := LogContextInfo{
+		Hash:     fileHash,
+		Filename: filename,
+		TZ:       r.FormValue("tz"),
+		Index:    index,
+		Lines:    make([]ContextLine, 0, len(lines)),
+	}
+	for i, line := range lines {
+		cl := ContextLine{
+			Index:  start + i,
+			Target: start+i == index,
+			Fields: line,
+		}
+		if ts, ok := logCtx.Timestamp(start + i); ok {
+			cl.Timestamp = displayTime(ts, loc)
+		}
+		info.Lines = append(info.Lines, cl)
+	}
+
+	h.renderFragment(w, r, "logContext", &info)
+}
+
+func (h *Handler) handleGetInspectLogTimeline(w http.ResponseWriter, r *http.Request) {
+	type TimelineLine struct {
+		Filename  string
+		Index     int
+		Target    bool
+		Timestamp time.Time
+		Time      string
+		Offset    time.Duration
+		Fields    collections.Fields
+	}
+	type LogTimelineInfo struct {
+		Hash      string
+		Filename  string
+		TZ        string
+		Index     int
+		Timestamp string
+		Window    time.Duration
+		Lines     []TimelineLine
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+	filename := r.FormValue("filename")
+
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		h.renderError(w, r, badRequest(fmt.Errorf("invalid index: %w", err)))
+		return
+	}
+	window := defaultTimelineWindow
+	if v := r.FormValue("window"); v != "" {
+		if window, err = time.ParseDuration(v); err != nil || window < 0 {
+			h.renderError(w, r, badRequest(fmt.Errorf("invalid window: %q", v)))
+			return
+		}
+	}
+
+	loc, err := parseTimezone(r.FormValue("tz"))
+	if err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Int("index", index).Dur("window", window).Msg("Requesting log timeline")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+	// The timeline interleaves every log, which are parsed in the background.
+	parsed, ok := h.awaitLogs(w, r, jobTargetLogContext, *r.URL, s)
+	if !ok {
+		return
+	}
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, r.FormValue("parser"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	center, ok := logCtx.Timestamp(index)
+	if !ok {
+		h.renderError(w, r, badRequest(fmt.Errorf("line %d in %q has no valid %s", index, filename, logs.TimestampField)))
+		return
+	}
+	// Clock offsets of files are applied to their timestamps, aligning logs
+	// written with skewed clocks.
+	center = center.Add(s.Offset(filename))
+
+	info := LogTimelineInfo{
+		Hash:      fileHash,
+		Filename:  filename,
+		TZ:        r.FormValue("tz"),
+		Index:     index,
+		Timestamp: displayTime(center, loc),
+		Window:    window,
+	}
+	for _, log := range parsed[s.Hash] {
+		if log.Err != nil {
+			// Keep going, a single unreadable file shouldn't hide the rest.
+			PropsFromContext(r.Context()).AppendError(log.Err)
+			continue
+		}
+		entry, entryCtx := log.Entry, log.Context
+		offset := s.Offset(entry.Filename)
+		indices := entryCtx.ViewTimeRange(center.Add(-window-offset), center.Add(window-offset))
+		for i, line := range entryCtx.View(indices...) {
+			ts, _ := entryCtx.Timestamp(indices[i])
+			ts = ts.Add(offset)
+			info.Lines = append(info.Lines, TimelineLine{
+				Filename:  entry.Filename,
+				Index:     indices[i],
+				Target:    entry.Filename == filename && indices[i] == index,
+				Timestamp: ts,
+				Time:      displayTime(ts, loc),
+				Offset:    offset,
+				Fields:    line,
+			})
+		}
+	}
+	sort.SliceStable(info.Lines, func(i, j int) bool {
+		return info.Lines[i].Timestamp.Before(info.Lines[j].Timestamp)
+	})
+
+	h.renderFragment(w, r, "logTimeline", &info)
+}
+
+// findingsResult is the result of a job evaluating the diagnostics rules. Err
+// holds the errors of files which couldn't be read, which don't fail the job.
+type findingsResult struct {
+	Findings []rules.Finding
+	Err      error
+}
+
+// findingsJob returns the job evaluating the diagnostics rules against a
+// session's bundle, starting it the first time. The job is kept by the session,
+// so its result is reused by later requests.
+func (h *Handler) findingsJob(s *session.Session) *jobs.Job {
+	return s.Findings(func() *jobs.Job {
+		return h.jobs.Submit(s.Hash+"/findings", "Evaluating diagnostics rules", func(ctx context.Context, j *jobs.Job) (any, error) {
+			var total int64
+			for _, entry := range s.Viewer.GetLogs() {
+				total += fileSize(s, entry.Filename)
+			}
+			j.SetTotal(total)
+
+			findings, err := h.evaluateRules(ctx, s, j)
+
+			return findingsResult{Findings: findings, Err: err}, nil
+		})
+	})
+}
+
+// evaluateRules runs the diagnostics rules against a session's bundle,
+// reporting the progress of parsing its logs to j.
+func (h *Handler) evaluateRules(ctx context.Context, s *session.Session, j *jobs.Job) ([]rules.Finding, error) {
+	return rules.Evaluate(h.rules, s.Viewer, func(filename string) (*logs.Context, error) {
+		return h.loadLogContextInJob(ctx, s, filename, j)
+	})
+}
+
+// loadLogContextInJob returns the parsed log context for filename like
+// loadLogContext, reporting the progress of parsing it to j. The log is parsed
+// by j itself, as waiting for other jobs could hold every worker.
+func (h *Handler) loadLogContextInJob(ctx context.Context, s *session.Session, filename string, j *jobs.Job) (*logs.Context, error) {
+	detection, err := detectLog(s, filename, "")
+	if err != nil {
+		return nil, err
+	}
+	key := session.LogCacheKey{Filename: filename, Parser: detection.Parser}
+	if logCtx, ok := s.Logs.Get(key); ok {
+		j.Add(fileSize(s, filename))
+		return logCtx, nil
+	}
+
+	return s.Logs.Load(key, func() (*logs.Context, error) {
+		return h.parseLogContext(ctx, s, key, j)
+	})
+}
+
+// parsedLog is a log parsed by a job started with startLogsJob. Err is set if
+// the log couldn't be parsed.
+type parsedLog struct {
+	Entry   logs.Entry
+	Context *logs.Context
+	Err     error
+}
+
+// logsJobKey returns the key of the job parsing every log of the sessions.
+func logsJobKey(sessions []*session.Session) string {
+	hashes := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		hashes = append(hashes, s.Hash)
+	}
+
+	return strings.Join(hashes, ",") + "/logs"
+}
+
+// startLogsJob starts a job parsing every log of the sessions' bundles in the
+// background. Its result maps the hash of each session to its logs, in the order
+// of the bundle. If the logs are already being parsed, the running job is
+// returned.
+func (h *Handler) startLogsJob(sessions []*session.Session) *jobs.Job {
+	return h.jobs.Submit(logsJobKey(sessions), "Parsing logs", func(ctx context.Context, j *jobs.Job) (any, error) {
+		var total int64
+		for _, s := range sessions {
+			for _, entry := range s.Viewer.GetLogs() {
+				total += fileSize(s, entry.Filename)
+			}
+		}
+		j.SetTotal(total)
+
+		result := make(map[string][]parsedLog, len(sessions))
+		for _, s := range sessions {
+			for _, entry := range s.Viewer.GetLogs() {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				logCtx, err := h.loadLogContextInJob(ctx, s, entry.Filename, j)
+				result[s.Hash] = append(result[s.Hash], parsedLog{Entry: entry, Context: logCtx, Err: err})
+			}
+		}
+
+		return result, nil
+	})
+}
+
+// awaitLogs returns the logs of the sessions' bundles, parsed in the background
+// by a job. While the job is running, its progress is rendered into the element
+// with the target id instead and false is returned. The progress view then loads
+// next, with the job added so its result is used.
+func (h *Handler) awaitLogs(w http.ResponseWriter, r *http.Request, target string, next url.URL, sessions ...*session.Session) (map[string][]parsedLog, bool) {
+	job, ok := h.jobs.Get(r.FormValue("job"))
+	if !ok || job.Key() != logsJobKey(sessions) {
+		job = h.startLogsJob(sessions)
+	}
+	if !awaitJob(job) {
+		query := next.Query()
+		query.Set("job", job.ID)
+		next.RawQuery = query.Encode()
+		h.renderJob(w, r, job, target, next.RequestURI())
+		return nil, false
+	}
+	result, err := job.Result()
+	if err != nil {
+		h.renderError(w, r, err)
+		return nil, false
+	}
+
+	return result.(map[string][]parsedLog), true
+}
+
+// awaitJob waits a short while for a job to finish, returning false if it is
+// still running.
+func awaitJob(job *jobs.Job) bool {
+	timer := time.NewTimer(defaultJobWait)
+	defer timer.Stop()
+
+	select {
+	case <-job.Done():
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// loadAgentState parses the agent state file of a session's bundle. Older
+// bundles have no state file, which is not an error, so false is returned
+// instead.
+func loadAgentState(s *session.Session) (state.AgentState, bool, error) {
+	file, err := s.Viewer.OpenFile(state.Filename)
+	if err != nil {
+		return state.AgentState{}, false, nil
+	}
+	defer file.Close()
+
+	agentState, err := state.Parse(file)
+	if err != nil {
+		return state.AgentState{}, false, err
+	}
+
+	return agentState, true, nil
+}
+
+// loadPolicy parses a policy file of a session's bundle.
+func loadPolicy(s *session.Session, filename string) (policy.Policy, error) {
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	defer file.Close()
+
+	return policy.Parse(file)
+}
+
+// componentLogsURL returns the URL of the agent log filtered to the lines of a
+// component, or an empty string if the bundle has no agent log. Components log
+// through the agent, tagged with their ID.
+func componentLogsURL(s *session.Session, fileHash string, componentID string) string {
+	for _, v := range s.Viewer.GetLogs() {
+		if v.Component != logs.ComponentAgent {
+			continue
+		}
+
+		filters, _ := json.Marshal([]*logs.TextFilter{{Operator: logs.FilterOpEquals, Field: "component.id", Value: componentID}})
+
+		return "/inspect/log/" + fileHash + "?" + url.Values{
+			"filename": {v.Filename},
+			"filters":  {string(filters)},
+		}.Encode()
+	}
+
+	return ""
+}
+
+// loadGoroutineDump parses the goroutine dump in a file. For logs, index is the
+// line the dump starts at; for other files it is ignored.
+func (h *Handler) loadGoroutineDump(ctx context.Context, s *session.Session, filename string, index int) ([]*goroutine.Goroutine, error) {
+	for _, entry := range s.Viewer.GetLogs() {
+		if entry.Filename != filename {
+			continue
+		}
+
+		logCtx, err := h.loadLogContext(ctx, s, filename, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, dump := range goroutine.FindInMessages(logMessages(logCtx)) {
+			if dump.Index == index {
+				return dump.Goroutines, nil
+			}
+		}
+
+		return nil, nil
+	}
+
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return goroutine.Parse(file)
+}
+
+// logMessages returns the message of each line of a log.
+func logMessages(logCtx *logs.Context) []string {
+	lines := logCtx.ViewAll()
+	messages := make([]string, len(lines))
+	for i, line := range lines {
+		messages[i], _ = line.GetString("message")
+	}
+
+	return messages
+}
+
+// findGoroutineDumpFiles returns the files in a bundle, other than its logs,
+// configs and profiles, that contain a goroutine dump.
+func findGoroutineDumpFiles(viewer bundle.Viewer) []string {
+	known := collections.NewSet[string](viewer.MetaFiles()...)
+	for _, v := range viewer.GetConfigs() {
+		known.Add(v.Filename)
+	}
+	for _, v := range viewer.GetLogs() {
+		known.Add(v.Filename)
+	}
+
+	var files []string
+	_ = viewer.Walk("", func(file *zip.File) error {
+		if file.FileInfo().IsDir() || known.Has(file.Name) || bundle.IsProfile(file.Name) {
+			return nil
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		head := make([]byte, goroutineDumpSniffSize)
+		n, _ := io.ReadFull(f, head)
+		if goroutine.IsDump(string(head[:n])) {
+			files = append(files, file.Name)
+		}
+
+		return nil
+	})
+
+	return files
+}
+
+// parseTextFilters parses a JSON encoded list of text filters. An empty value
+// results in no filters.
+func parseTextFilters(value string) ([]*logs.TextFilter, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var filters []*logs.TextFilter
+	if err := json.Unmarshal([]byte(value), &filters); err != nil {
+		return nil, fmt.Errorf("invalid filters: %w", err)
+	}
+
+	return filters, nil
+}
+
+// parseFormTime parses a time from a form value, accepting the layouts of
+// logs.ParseTimeExpr, including times relative to now, and the format used by
+// datetime-local inputs, which is taken to be UTC. An empty value results in a
+// zero time.
+func parseFormTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse("2006-01-02T15:04", value); err == nil {
+		return t, nil
+	}
+
+	return logs.ParseTimeExpr(value, now)
+}
+
+// parseTimezone parses the timezone timestamps are displayed in, such as "UTC"
+// or "Europe/Berlin". An empty value keeps timestamps as they were logged,
+// returning a nil location.
+func parseTimezone(value string) (*time.Location, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	return loc, nil
+}
+
+// getSession returns the session for the bundle with the given hash.
+func (h *Handler) getSession(fileHash string) (*session.Session, bool) {
+	h.sessionsMu.RLock()
+	defer h.sessionsMu.RUnlock()
+
+	s, ok := h.sessions[fileHash]
+
+	return s, ok
+}
+
+// loadLogContext returns the parsed log context for filename, parsing the file
+// and caching the result in the session if needed. The parser is detected from
+// the file unless one is given. Parsing continues in the background if ctx is
+// done first.
+func (h *Handler) loadLogContext(ctx context.Context, s *session.Session, filename string, parser string) (*logs.Context, error) {
+	detection, err := detectLog(s, filename, parser)
+	if err != nil {
+		return nil, err
+	}
+	key := session.LogCacheKey{Filename: filename, Parser: detection.Parser}
+	if logCtx, ok := s.Logs.Get(key); ok {
+		return logCtx, nil
+	}
+
+	result, err := h.startLogContextJob(s, key).Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*logs.Context), nil
+}
+
+// detectLog detects the parser and compression of a log file. If parser is not
+// empty, it is used instead of the detected parser. The parser of a rotated log
+// is detected from its first segment.
+func detectLog(s *session.Session, filename string, parser string) (logs.Detection, error) {
+	if rotated, ok := logs.FindRotated(s.Viewer.GetLogs(), filename); ok {
+		detection, err := detectLog(s, rotated.Segments[0], parser)
+		// Segments may be compressed differently.
+		detection.Compression = ""
+
+		return detection, err
+	}
+
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		return logs.Detection{}, err
+	}
+	defer file.Close()
+
+	_, detection, err := logs.Detect(file, filename, parser)
+
+	return detection, err
+}
+
+// startLogContextJob starts a job parsing a log file in the background. If the
+// file is already being parsed, the running job is returned.
+func (h *Handler) startLogContextJob(s *session.Session, key session.LogCacheKey) *jobs.Job {
+	return h.jobs.Submit(logContextJobKey(s, key), "Parsing "+key.Filename, func(ctx context.Context, j *jobs.Job) (any, error) {
+		return s.Logs.Load(key, func() (*logs.Context, error) {
+			if rotated, ok := logs.FindRotated(s.Viewer.GetLogs(), key.Filename); ok {
+				return h.mergeRotated(ctx, s, rotated, key.Parser, j)
+			}
+
+			j.SetTotal(fileSize(s, key.Filename))
+
+			return h.parseLogContext(ctx, s, key, j)
+		})
+	})
+}
+
+// mergeRotated parses the segments of a rotated log, reporting progress to j,
+// and merges them into a single context.
+func (h *Handler) mergeRotated(ctx context.Context, s *session.Session, rotated logs.RotatedLog, parser string, j *jobs.Job) (*logs.Context, error) {
+	var total int64
+	for _, filename := range rotated.Segments {
+		total += fileSize(s, filename)
+	}
+	j.SetTotal(total)
+
+	segments := make([]*logs.Context, 0, len(rotated.Segments))
+	for _, filename := range rotated.Segments {
+		key := session.LogCacheKey{Filename: filename, Parser: parser}
+		segment, ok := s.Logs.Get(key)
+		if ok {
+			j.Add(fileSize(s, filename))
+		} else {
+			var err error
+			segment, err = s.Logs.Load(key, func() (*logs.Context, error) {
+				return h.parseLogContext(ctx, s, key, j)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse segment %q: %w", filename, err)
+			}
+		}
+		segments = append(segments, segment)
+	}
+
+	return logs.MergeSegments(rotated.Segments, segments), nil
+}
+
+// fileSize returns the size of a file in a session's bundle, or 0 if it cannot
+// be determined.
+func fileSize(s *session.Session, filename string) int64 {
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// logContextJobKey returns the key of jobs parsing a log file.
+func logContextJobKey(s *session.Session, key session.LogCacheKey) string {
+	return s.Hash + "/" + key.String()
+}
+
+// parseLogContext parses a log file, reporting progress and a preview of its
+// first lines to j.
+func (h *Handler) parseLogContext(ctx context.Context, s *session.Session, key session.LogCacheKey, j *jobs.Job) (*logs.Context, error) {
+	file, err := s.Viewer.OpenFile(key.Filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r, detection, err := logs.Detect(jobs.NewReader(ctx, j, file), key.Filename, key.Parser)
+	if err != nil {
+		return nil, err
+	}
+	p, err := logs.NewParser(detection.Parser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Progress is reported for the bytes of the file, while the preview shows
+	// its decompressed lines.
+	logCtx, err := p.Parse(jobs.NewPreviewReader(j, r))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := logCtx.Stats()
+	logger.Debug().
+		Str("filename", key.Filename).
+		Str("parser", detection.Parser).
+		Str("compression", detection.Compression).
+		Int("lines", stats.Lines).
+		Int("index_tokens", stats.IndexTokens).
+		Int64("index_bytes", stats.IndexBytes).
+		Int64("memory_bytes", logCtx.MemoryUsage()).
+		Msg("Parsed log file")
+
+	return logCtx, nil
+}
+
+func (h *Handler) loadTemplates() error {
+	var err error
+
+	tmplFuncs := template.FuncMap{
+		"configTypeToStr":   func(t config.Type) string { return t.String() },
+		"isPolicyConfig":    func(t config.Type) bool { return t == config.TypeAgentPolicy },
+		"logTypeToStr":      func(t logs.Type) string { return t.String() },
+		"logComponentToStr": func(c logs.Component) string { return c.String() },
+		"severityToStr":     func(s rules.Severity) string { return s.String() },
+		"stateToStr":        func(s state.State) string { return s.String() },
+		"flameChild": func(node, parent *profile.FlameNode) map[string]any {
+			return map[string]any{
+				"Node":  node,
+				"Width": strconv.FormatFloat(node.Fraction(parent.Value)*100, 'f', 2, 64),
+			}
+		},
+		// lineNumber converts a line index to the line number shown in log tables.
+		"lineNumber": func(index int) int { return index + 1 },
+		"percent": func(value, total int64) string {
+			if total == 0 {
+				return "0.00"
+			}
+			return strconv.FormatFloat(float64(value)/float64(total)*100, 'f', 2, 64)
+		},
+		"fieldStr": func(f collections.Fields, key string) string {
+			if v, ok := f.Get(key); ok {
+				return fmt.Sprintf("%v", v)
+			}
+			return ""
+		},
+		"marshalJSON": func(v any) template.JS {
+			data, mErr := json.Marshal(v)
+			if mErr != nil {
+				panic(mErr)
+			}
+			return template.JS(data)
+		},
+		"makeTableData": func(entries []collections.Fields, indices []int, columns []tableColumn) template.JS {
+			tableData := make([]map[string]any, 0, len(entries))
+
+			for i, entry := range entries {
+				entryData := make(map[string]any, len(columns)+1)
+				flat := entry.Flatten()
+
+				// Row IDs are line numbers, starting at 1.
+				entryData["id"] = indices[i] + 1
+				for _, col := range columns {
+					entryData[col.Key()] = col.Format(flat[col.Field])
+				}
+				tableData = append(tableData, entryData)
+			}
+
+			data, mErr := json.Marshal(tableData)
+			if mErr != nil {
+				panic(mErr)
+			}
+			return template.JS(data)
+		},
+		"makeTableColumns": func(columns []tableColumn) template.JS {
+			type tableColumnData struct {
+				Title              string         `json:"title"`
+				Field              string         `json:"field"`
+				Sorter             string         `json:"sorter,omitempty"`
+				HozAlign           string         `json:"hozAlign,omitempty"`
+				Formatter          string         `json:"formatter,omitempty"`
+				FormatterParams    map[string]any `json:"formatterParams,omitempty"`
+				HeaderFilter       string         `json:"headerFilter,omitempty"`
+				HeaderFilterParams map[string]any `json:"headerFilterParams,omitempty"`
+			}
+
+			tableColumns := make([]tableColumnData, 0, len(columns))
+			for _, col := range columns {
+				tcd := tableColumnData{
+					Title: col.Field,
+					Field: col.Key(),
+				}
+				switch col.Type {
+				case columnTypeNumber:
+					tcd.Sorter = "number"
+					tcd.HozAlign = "right"
+					tcd.Formatter = "money"
+					tcd.FormatterParams = map[string]any{
+						"thousand":  ",",
+						"precision": false,
+					}
+				case columnTypeTime:
+					tcd.Sorter = "string"
+				}
+				if col.Field == "log.level" {
+					tcd.HeaderFilter = "list"
+					tcd.HeaderFilterParams = map[string]any{
+						"valuesLookup": true,
+						"clearable":    true,
+					}
+				}
+
+				tableColumns = append(tableColumns, tcd)
+			}
+
+			data, mErr := json.Marshal(tableColumns)
+			if mErr != nil {
+				panic(mErr)
+			}
+			return template.JS(data)
+		},
+	}
+
+	h.indexTmpl, err = template.New("base").Funcs(tmplFuncs).ParseFS(ui.FS, "templates/layouts/*.gohtml", "templates/index.gohtml")
+	if err != nil {
+		return fmt.Errorf("unable to parse template: %w", err)
+	}
+	h.errorTmpl, err = template.New("base").Funcs(tmplFuncs).ParseFS(ui.FS, "templates/layouts/*.gohtml", "templates/error.gohtml", "templates/fragments/error.gohtml")
+	if err != nil {
+		return fmt.Errorf("unable to parse template: %w", err)
+	}
+	h.fragments, err = template.New("bundleDetails").Funcs(tmplFuncs).ParseFS(ui.FS, "templates/fragments/*.gohtml")
+	if err != nil {
+		return fmt.Errorf("unable to parse template: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Handler) Close() {
+	h.jobs.Close()
+	h.uploads.Close()
+
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+
+	for _, v := range h.sessions {
+		_ = v.Viewer.Close()
+		if err := os.Remove(v.Filename); err != nil {
+			logger.Error().Err(err).Str("filename", v.Filename).Msg("Failed to remove file")
+		} else {
+			logger.Debug().Str("filename", v.Filename).Msg("Removed file")
+		}
+	}
+}
+
+func (h *Handler) loadRules(dir string) error {
+	var err error
+
+	if h.rules, err = rules.Builtin(); err != nil {
+		return fmt.Errorf("unable to load built-in rules: %w", err)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	dirRules, err := rules.LoadFS(os.DirFS(dir), ".")
+	if err != nil {
+		return fmt.Errorf("unable to load rules from %q: %w", dir, err)
+	}
+	h.rules = append(h.rules, dirRules...)
+	logger.Debug().Str("dir", dir).Int("rules", len(dirRules)).Msg("Loaded rules")
+
+	return nil
+}
+
+func NewHandler(opts Options) (*Handler, error) {
 	h := &Handler{
 		Mux:           chi.NewRouter(),
-		maxUploadSize: 100 * 1024 * 1024, // 100 MB
+		maxUploadSize: opts.MaxUploadSize,
+		logCacheSize:  opts.LogCacheSize,
+		uploads:       upload.NewManager("", bundleFilePattern, opts.MaxUploadSize),
 		sessions:      map[string]*session.Session{},
+		jobs:          jobs.NewManager(opts.ParseWorkers),
+
+		sessionCollections: map[string]*session.Collection{},
 	}
 	h.Use(
-		h.middlewareRecovery,
 		h.middlewareCtxProps,
+		h.middlewareRecovery,
 		h.middlewareLogger,
 		middleware.StripSlashes,
 	)
@@ -501,12 +1988,39 @@ func NewHandler() (*Handler, error) {
 	if err := h.loadTemplates(); err != nil {
 		return nil, err
 	}
+	if err := h.loadRules(opts.RulesDir); err != nil {
+		return nil, err
+	}
 
 	// Routes
 	h.Get("/", h.handleGetRoot)
 	h.Post("/upload", h.handlePostUpload)
+	h.Route("/uploads", h.routeUploads)
+	h.Post("/collection", h.handlePostCollection)
+	h.Get("/collection/{id}", h.handleGetCollection)
+	h.Post("/collection/{id}/upload", h.handlePostCollectionUpload)
+	h.Get("/collection/{id}/search", h.handleGetCollectionSearch)
+	h.Get("/inspect/bundle/{hash}", h.handleGetInspectBundle)
+	h.Get("/inspect/findings/{hash}", h.handleGetInspectFindings)
+	h.Get("/inspect/findings/{hash}/summary", h.handleGetFindingsSummary)
+	h.Get("/inspect/state/{hash}", h.handleGetInspectState)
 	h.Get("/inspect/config/{hash}", h.handleGetInspectConfig)
+	h.Get("/inspect/policy/{hash}", h.handleGetInspectPolicy)
+	h.Get("/inspect/profile/{hash}", h.handleGetInspectProfile)
+	h.Get("/inspect/metrics/{hash}", h.handleGetInspectMetrics)
+	h.Get("/inspect/goroutines/{hash}", h.handleGetInspectGoroutines)
+	h.Get("/inspect/goroutines/{hash}/dump", h.handleGetInspectGoroutineDump)
+	h.Get("/inspect/skew/{hash}", h.handleGetInspectSkew)
+	h.Post("/inspect/skew/{hash}/offset", h.handlePostInspectSkewOffset)
 	h.Get("/inspect/log/{hash}", h.handleGetInspectLog)
+	h.Get("/jobs/{id}", h.handleGetJob)
+	h.Post("/inspect/log/{hash}/columns", h.handlePostInspectLogColumns)
+	h.Get("/inspect/log/{hash}/entry", h.handleGetInspectLogEntry)
+	h.Get("/inspect/log/{hash}/context", h.handleGetInspectLogContext)
+	h.Get("/inspect/log/{hash}/timeline", h.handleGetInspectLogTimeline)
+	h.Get("/export/log/{hash}", h.handleGetExportLog)
+	h.Post("/export/bundle/{hash}", h.handlePostExportBundle)
+	h.Route("/api/v1", h.routeAPI)
 
 	return h, nil
 }
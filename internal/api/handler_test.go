@@ -0,0 +1,191 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/jobs"
+
+	_ "github.com/taylor-swanson/sawmill/internal/bundle/v1"
+	_ "github.com/taylor-swanson/sawmill/internal/component/logs/ndjson"
+)
+
+// testBundleLog is the log of the test bundle.
+const testBundleLog = "logs/elastic-agent-20230104.ndjson"
+
+// newTestHandler returns a handler which is closed when the test finishes.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	opts := DefaultOptions()
+	opts.ParseWorkers = 2
+	opts.MaxUploadSize = 1024 * 1024
+	h, err := NewHandler(opts)
+	require.NoError(t, err)
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+// makeTestBundle returns a zipped bundle of an agent on hostname, whose log
+// contains lines.
+func makeTestBundle(t *testing.T, hostname string, lines ...string) []byte {
+	t.Helper()
+
+	files := map[string]string{
+		"meta/elastic-agent-version.yaml": "version: 8.6.0\ncommit: b79a5db77b5d6ffab9855234f8371d9e53978a24\n",
+		"meta/filebeat-default.yaml":      "hostname: " + hostname + "\n",
+		"config/filebeat.yaml":            "filebeat.inputs: []\n",
+	}
+	var log bytes.Buffer
+	for _, line := range lines {
+		log.WriteString(line + "\n")
+	}
+	files[testBundleLog] = log.String()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+// bundleHash returns the hash identifying the session of a bundle.
+func bundleHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// newUploadRequest returns a multipart request uploading bundles to target,
+// along with other form values.
+func newUploadRequest(t *testing.T, method string, target string, values map[string]string, bundles ...[]byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range values {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+	for i, data := range bundles {
+		w, err := mw.CreateFormFile("file", fmt.Sprintf("bundle-%d.zip", i))
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(method, target, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return req
+}
+
+// serve sends a request to h, returning the recorded response.
+func serve(h *Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestHandleGetInspect_ParseLogsInBackground(t *testing.T) {
+	h := newTestHandler(t)
+	data := makeTestBundle(t, "web-01",
+		`{"@timestamp":"2023-01-04T10:00:00Z","log.level":"info","message":"a"}`,
+		`{"@timestamp":"2023-01-04T10:01:00Z","log.level":"error","message":"b"}`,
+	)
+	rec := serve(h, newUploadRequest(t, http.MethodPost, "/upload", nil, data))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	fileHash := bundleHash(data)
+	rec = serve(h, newUploadRequest(t, http.MethodPost, "/collection", nil, data))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var collectionID string
+	for collectionID = range h.sessionCollections {
+	}
+
+	tests := map[string]struct {
+		target string
+		id     string
+		want   string
+	}{
+		"goroutines": {
+			target: "/inspect/goroutines/" + fileHash,
+			id:     jobTargetGoroutines,
+			want:   `id="goroutines"`,
+		},
+		"skew": {
+			target: "/inspect/skew/" + fileHash,
+			id:     jobTargetSkew,
+			want:   "Clock Skew",
+		},
+		"timeline": {
+			target: "/inspect/log/" + fileHash + "/timeline?" + url.Values{"filename": {testBundleLog}, "index": {"1"}}.Encode(),
+			id:     jobTargetLogContext,
+			want:   "All files within",
+		},
+		"collection_search": {
+			target: "/collection/" + collectionID + "/search?" + url.Values{"filter": {"log.level=error"}}.Encode(),
+			id:     jobTargetCollectionSearch,
+			want:   "<b>1</b> matching lines",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Hold every worker, so the logs can't be parsed yet.
+			release := make(chan struct{})
+			for i := 0; i < 2; i++ {
+				hold := h.jobs.Submit(fmt.Sprintf("%s/hold-%d", name, i), "Holding", func(ctx context.Context, j *jobs.Job) (any, error) {
+					<-release
+					return nil, nil
+				})
+				require.Eventually(t, func() bool {
+					return hold.Status() == jobs.StatusRunning
+				}, time.Second, time.Millisecond)
+			}
+
+			rec := serve(h, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			close(release)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			body := rec.Body.String()
+			require.Contains(t, body, `<div id="`+tc.id+`">`)
+			require.Contains(t, body, "Parsing logs")
+
+			// The progress view polls the job, then reloads the view with it.
+			poll := regexp.MustCompile(`hx-get="(/jobs/[^"]+)"`).FindStringSubmatch(body)
+			require.NotNil(t, poll, body)
+			u, err := url.Parse(html.UnescapeString(poll[1]))
+			require.NoError(t, err)
+			require.Equal(t, tc.id, u.Query().Get("target"))
+			next, err := url.Parse(u.Query().Get("next"))
+			require.NoError(t, err)
+			job, ok := h.jobs.Get(next.Query().Get("job"))
+			require.True(t, ok)
+			_, err = job.Wait(context.Background())
+			require.NoError(t, err)
+
+			rec = serve(h, httptest.NewRequest(http.MethodGet, next.String(), nil))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			require.Contains(t, rec.Body.String(), tc.want)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/taylor-swanson/sawmill/internal/jobs"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+// Elements which job views may replace.
+const (
+	jobTargetDetailView       = "detail-view"
+	jobTargetFindings         = "findings"
+	jobTargetGoroutines       = "goroutines"
+	jobTargetSkew             = "skew"
+	jobTargetLogContext       = "log-context"
+	jobTargetCollectionSearch = "collection-search"
+)
+
+func (h *Handler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	logger.Debug().Str("id", id).Msg("Requesting a job")
+
+	job, ok := h.jobs.Get(id)
+	if !ok {
+		h.renderError(w, r, fmt.Errorf("job %q %w", id, ErrNotFound))
+		return
+	}
+
+	target := r.FormValue("target")
+	switch target {
+	case jobTargetFindings, jobTargetGoroutines, jobTargetSkew, jobTargetLogContext, jobTargetCollectionSearch:
+	default:
+		target = jobTargetDetailView
+	}
+
+	h.renderJob(w, r, job, target, r.FormValue("next"))
+}
+
+// renderJob renders the progress of a job. The view refreshes itself until the
+// job has finished, then loads next into the element with the target id.
+func (h *Handler) renderJob(w http.ResponseWriter, r *http.Request, job *jobs.Job, target string, next string) {
+	type JobInfo struct {
+		ID       string
+		Name     string
+		Status   string
+		Progress jobs.Progress
+		Preview  []string
+		Err      error
+		PollURL  string
+		Next     string
+		Target   string
+	}
+
+	// Only follow up with views of this server.
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = ""
+	}
+
+	status := job.Status()
+	_, err := job.Result()
+
+	info := JobInfo{
+		ID:       job.ID,
+		Name:     job.Name,
+		Status:   status.String(),
+		Progress: job.Progress(),
+		Preview:  job.Preview(),
+		Err:      err,
+		PollURL:  "/jobs/" + job.ID + "?" + url.Values{"next": {next}, "target": {target}}.Encode(),
+		Next:     next,
+		Target:   target,
+	}
+
+	h.renderFragment(w, r, "job", &info)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// jsonNode is a node in a rendered JSON tree.
+type jsonNode struct {
+	// Key is the key of this node within its parent.
+	Key string
+	// Path is the full dotted path of this node, usable with collections.Fields.Get.
+	// It is empty for nodes within arrays, as these can't be addressed by path.
+	Path string
+	// Value is the JSON encoded value of a leaf node.
+	Value string
+	// FilterValue holds the raw value of a string, number or boolean leaf
+	// node, as used in filters.
+	FilterValue string
+	// IsFilterable is true if the leaf node can be filtered on by its value.
+	IsFilterable bool
+	// Type is the type of the field of an addressable node.
+	Type logs.FieldType
+	// IsArray is true if the node is an array.
+	IsArray bool
+	// Children holds the child nodes of an object or array.
+	Children []jsonNode
+}
+
+// IsLeaf returns true if the node holds a scalar value.
+func (n jsonNode) IsLeaf() bool {
+	return n.Children == nil
+}
+
+// makeJSONTree builds a tree of nodes from fields, sorted by key. The types of
+// fields are looked up by their path with fieldType.
+func makeJSONTree(fields collections.Fields, fieldType func(field string) logs.FieldType) []jsonNode {
+	return makeJSONObjectNodes("", true, fields, fieldType)
+}
+
+func makeJSONObjectNodes(parentPath string, addressable bool, m map[string]any, fieldType func(string) logs.FieldType) []jsonNode {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	nodes := make([]jsonNode, 0, len(keys))
+	for _, k := range keys {
+		var path string
+		if addressable {
+			path = k
+			if parentPath != "" {
+				path = parentPath + "." + k
+			}
+		}
+		nodes = append(nodes, makeJSONNode(k, path, addressable, m[k], fieldType))
+	}
+
+	return nodes
+}
+
+func makeJSONNode(key, path string, addressable bool, value any, fieldType func(string) logs.FieldType) jsonNode {
+	node := jsonNode{Key: key, Path: path}
+	if path != "" {
+		node.Type = fieldType(path)
+	}
+
+	switch v := value.(type) {
+	case collections.Fields:
+		node.Children = makeJSONObjectNodes(path, addressable, v, fieldType)
+	case map[string]any:
+		node.Children = makeJSONObjectNodes(path, addressable, v, fieldType)
+	case []any:
+		node.IsArray = true
+		node.Children = make([]jsonNode, 0, len(v))
+		for i, elem := range v {
+			node.Children = append(node.Children, makeJSONNode("["+strconv.Itoa(i)+"]", "", false, elem, fieldType))
+		}
+	case string:
+		node.IsFilterable = true
+		node.FilterValue = v
+		node.Value = jsonString(v)
+	case float64:
+		node.IsFilterable = true
+		node.FilterValue = strconv.FormatFloat(v, 'f', -1, 64)
+		node.Value = jsonString(v)
+	case bool:
+		node.IsFilterable = true
+		node.FilterValue = strconv.FormatBool(v)
+		node.Value = jsonString(v)
+	default:
+		node.Value = jsonString(v)
+	}
+
+	return node
+}
+
+func jsonString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
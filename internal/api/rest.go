@@ -0,0 +1,418 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+	"github.com/taylor-swanson/sawmill/internal/session"
+)
+
+const (
+	// apiPrefix is the path prefix of the JSON API routes.
+	apiPrefix = "/api/"
+	// defaultQueryLimit is the number of lines returned by a log query if no
+	// limit is given.
+	defaultQueryLimit = 100
+	// maxQueryLimit is the largest number of lines returned by a log query.
+	maxQueryLimit = 10000
+)
+
+// openAPIDocument describes the JSON API.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// apiProblem is the error returned by every JSON API endpoint, as problem
+// details (RFC 9457).
+type apiProblem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type apiBundle struct {
+	Hash             string      `json:"hash"`
+	OriginalFilename string      `json:"original_filename"`
+	Info             bundle.Info `json:"info"`
+}
+
+type apiConfig struct {
+	Filename string `json:"filename"`
+	Type     string `json:"type"`
+}
+
+type apiConfigContent struct {
+	apiConfig
+	Content string `json:"content"`
+}
+
+type apiLog struct {
+	Filename  string `json:"filename"`
+	Type      string `json:"type"`
+	Component string `json:"component"`
+	// Segments are the files of a rotated log, ordered by name.
+	Segments []string `json:"segments,omitempty"`
+}
+
+type apiLogLine struct {
+	Index     int                `json:"index"`
+	Timestamp string             `json:"timestamp,omitempty"`
+	Fields    collections.Fields `json:"fields"`
+}
+
+type apiLogQuery struct {
+	Filename string       `json:"filename"`
+	Parser   string       `json:"parser"`
+	Total    int          `json:"total"`
+	Offset   int          `json:"offset"`
+	Limit    int          `json:"limit"`
+	Lines    []apiLogLine `json:"lines"`
+}
+
+type apiLogStats struct {
+	Filename string `json:"filename"`
+	Parser   string `json:"parser"`
+	logs.Stats
+	FieldTypes  map[string]string `json:"field_types"`
+	MemoryBytes int64             `json:"memory_bytes"`
+}
+
+// writeJSON writes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// writeAPIError records err with the request and writes it as problem details,
+// with the status of its kind.
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	PropsFromContext(r.Context()).AppendError(err)
+
+	status := errorStatus(err)
+	writeProblem(w, r, status, errorMessage(err, status))
+}
+
+// writeProblem writes problem details with the given status and detail.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	data, _ := json.Marshal(apiProblem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: PropsFromContext(r.Context()).RequestID(),
+	})
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// apiSession returns the session of the request's bundle, writing a not found
+// error if there is none.
+func (h *Handler) apiSession(w http.ResponseWriter, r *http.Request) (*session.Session, bool) {
+	fileHash := chi.URLParam(r, "hash")
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		writeAPIError(w, r, bundleNotFound(fileHash))
+	}
+
+	return s, ok
+}
+
+// apiLogFile returns the log file of the request, which must be a log of the
+// bundle or a rotated log, writing an error if it is not.
+func apiLogFile(w http.ResponseWriter, r *http.Request, s *session.Session) (string, bool) {
+	filename := r.FormValue("filename")
+	if filename == "" {
+		writeAPIError(w, r, badRequest(errors.New("missing filename")))
+		return "", false
+	}
+	entries := s.Viewer.GetLogs()
+	for _, entry := range entries {
+		if entry.Filename == filename {
+			return filename, true
+		}
+	}
+	if _, ok := logs.FindRotated(entries, filename); ok {
+		return filename, true
+	}
+	writeAPIError(w, r, fmt.Errorf("log %q %w", filename, ErrNotFound))
+
+	return "", false
+}
+
+// apiLogContext parses the log file of the request with its parser, writing an
+// error if it can't be.
+func (h *Handler) apiLogContext(w http.ResponseWriter, r *http.Request, s *session.Session, filename string) (*logs.Context, logs.Detection, bool) {
+	detection, err := detectLog(s, filename, r.FormValue("parser"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return nil, detection, false
+	}
+	logCtx, err := h.loadLogContext(r.Context(), s, filename, detection.Parser)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return nil, detection, false
+	}
+
+	return logCtx, detection, true
+}
+
+func newAPIBundle(s *session.Session) apiBundle {
+	return apiBundle{
+		Hash:             s.Hash,
+		OriginalFilename: s.OriginalFilename,
+		Info:             s.Viewer.Info(),
+	}
+}
+
+func (h *Handler) handleGetAPIOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+func (h *Handler) handlePostAPIBundles(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(maxFormMemory); err != nil {
+		writeAPIError(w, r, uploadError(err))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeAPIError(w, r, badRequest(fmt.Errorf("missing bundle file: %w", err)))
+		return
+	}
+	defer file.Close()
+
+	s, err := h.openSession(file, header.Filename)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	logger.Debug().Str("hash", s.Hash).Str("filename", header.Filename).Msg("Uploaded a bundle through the API")
+
+	writeJSON(w, r, http.StatusCreated, newAPIBundle(s))
+}
+
+func (h *Handler) handleGetAPIBundle(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newAPIBundle(s))
+}
+
+func (h *Handler) handleGetAPIConfigs(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+
+	entries := s.Viewer.GetConfigs()
+	configs := make([]apiConfig, 0, len(entries))
+	for _, entry := range entries {
+		configs = append(configs, apiConfig{Filename: entry.Filename, Type: entry.Type.String()})
+	}
+
+	writeJSON(w, r, http.StatusOK, configs)
+}
+
+func (h *Handler) handleGetAPIConfigContent(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+
+	filename := r.FormValue("filename")
+	var content *apiConfigContent
+	for _, entry := range s.Viewer.GetConfigs() {
+		if entry.Filename == filename {
+			content = &apiConfigContent{apiConfig: apiConfig{Filename: entry.Filename, Type: entry.Type.String()}}
+			break
+		}
+	}
+	if content == nil {
+		writeAPIError(w, r, fmt.Errorf("config %q %w", filename, ErrNotFound))
+		return
+	}
+
+	file, err := s.Viewer.OpenFile(filename)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	content.Content = string(data)
+
+	writeJSON(w, r, http.StatusOK, content)
+}
+
+func (h *Handler) handleGetAPILogs(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+
+	entries := s.Viewer.GetLogs()
+	rotated := logs.GroupRotated(entries)
+	list := make([]apiLog, 0, len(entries)+len(rotated))
+	for _, entry := range entries {
+		list = append(list, apiLog{Filename: entry.Filename, Type: entry.Type.String(), Component: entry.Component.String()})
+	}
+	for _, log := range rotated {
+		list = append(list, apiLog{Filename: log.Name, Type: log.Type.String(), Component: log.Component.String(), Segments: log.Segments})
+	}
+
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+// handleGetAPILogQuery returns the lines of a log matching every filter. Filters
+// are expressions in the form accepted by logs.ParseTextFilter.
+func (h *Handler) handleGetAPILogQuery(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+	filename, ok := apiLogFile(w, r, s)
+	if !ok {
+		return
+	}
+
+	offset, limit := 0, defaultQueryLimit
+	var err error
+	if v := r.FormValue("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			writeAPIError(w, r, badRequest(fmt.Errorf("invalid offset: %q", v)))
+			return
+		}
+	}
+	if v := r.FormValue("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 || limit > maxQueryLimit {
+			writeAPIError(w, r, badRequest(fmt.Errorf("invalid limit: %q, must be between 0 and %d", v, maxQueryLimit)))
+			return
+		}
+	}
+	loc, err := parseTimezone(r.FormValue("tz"))
+	if err != nil {
+		writeAPIError(w, r, badRequest(err))
+		return
+	}
+	textFilters := make([]*logs.TextFilter, 0, len(r.Form["filter"]))
+	for _, expr := range r.Form["filter"] {
+		f, err := logs.ParseTextFilter(expr)
+		if err != nil {
+			writeAPIError(w, r, badRequest(err))
+			return
+		}
+		textFilters = append(textFilters, f)
+	}
+
+	logCtx, detection, ok := h.apiLogContext(w, r, s, filename)
+	if !ok {
+		return
+	}
+	filters, err := logCtx.TypedFilters(s.CollectionTime(), textFilters...)
+	if err != nil {
+		writeAPIError(w, r, badRequest(err))
+		return
+	}
+
+	indices := logCtx.Filter(filters...)
+	result := apiLogQuery{
+		Filename: filename,
+		Parser:   detection.Parser,
+		Total:    len(indices),
+		Offset:   offset,
+		Limit:    limit,
+		Lines:    []apiLogLine{},
+	}
+	if offset < len(indices) {
+		indices = indices[offset:min(len(indices), offset+limit)]
+		for i, line := range logCtx.View(indices...) {
+			l := apiLogLine{Index: indices[i], Fields: line}
+			if ts, ok := logCtx.Timestamp(indices[i]); ok {
+				l.Timestamp = displayTime(ts, loc)
+			}
+			result.Lines = append(result.Lines, l)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+func (h *Handler) handleGetAPILogStats(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.apiSession(w, r)
+	if !ok {
+		return
+	}
+	filename, ok := apiLogFile(w, r, s)
+	if !ok {
+		return
+	}
+	logCtx, detection, ok := h.apiLogContext(w, r, s, filename)
+	if !ok {
+		return
+	}
+
+	stats := apiLogStats{
+		Filename:    filename,
+		Parser:      detection.Parser,
+		Stats:       logCtx.Stats(),
+		FieldTypes:  map[string]string{},
+		MemoryBytes: logCtx.MemoryUsage(),
+	}
+	for _, field := range stats.Fields {
+		stats.FieldTypes[field] = logCtx.FieldType(field).String()
+	}
+
+	writeJSON(w, r, http.StatusOK, stats)
+}
+
+// routeAPI adds the routes of the JSON API, version 1.
+func (h *Handler) routeAPI(r chi.Router) {
+	r.Get("/openapi.json", h.handleGetAPIOpenAPI)
+	r.Post("/bundles", h.handlePostAPIBundles)
+	r.Get("/bundles/{hash}", h.handleGetAPIBundle)
+	r.Get("/bundles/{hash}/configs", h.handleGetAPIConfigs)
+	r.Get("/bundles/{hash}/configs/content", h.handleGetAPIConfigContent)
+	r.Get("/bundles/{hash}/logs", h.handleGetAPILogs)
+	r.Get("/bundles/{hash}/logs/query", h.handleGetAPILogQuery)
+	r.Get("/bundles/{hash}/logs/stats", h.handleGetAPILogStats)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, r, fmt.Errorf("endpoint %s %s %w", r.Method, r.URL.Path, ErrNotFound))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed for %s", r.Method, r.URL.Path))
+	})
+}
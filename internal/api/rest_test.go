@@ -0,0 +1,316 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// testAPILog is the log of bundles uploaded in API tests.
+var testAPILog = []string{
+	`{"@timestamp":"2023-01-04T10:00:00.000Z","log.level":"info","message":"starting","count":1}`,
+	`{"@timestamp":"2023-01-04T10:00:01.000Z","log.level":"error","message":"failed","count":10}`,
+	`{"@timestamp":"2023-01-04T10:00:02.000Z","log.level":"error","message":"failed again","count":2}`,
+	`{"@timestamp":"2023-01-04T10:00:03.000Z","log.level":"error","message":"gave up","count":20}`,
+}
+
+// uploadAPIBundle uploads a bundle through the API, returning its hash.
+func uploadAPIBundle(t *testing.T, h *Handler) string {
+	t.Helper()
+
+	data := makeTestBundle(t, "web-01", testAPILog...)
+	rec := serve(h, newUploadRequest(t, http.MethodPost, "/api/v1/bundles", nil, data))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	return bundleHash(data)
+}
+
+// decodeJSON decodes the JSON body of a response with the given content type.
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, contentType string, v any) {
+	t.Helper()
+
+	require.Equal(t, contentType, rec.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+}
+
+func TestAPI_PostBundles(t *testing.T) {
+	h := newTestHandler(t)
+	data := makeTestBundle(t, "web-01", testAPILog...)
+
+	tests := map[string]struct {
+		req        *http.Request
+		wantStatus int
+	}{
+		"bundle": {
+			req:        newUploadRequest(t, http.MethodPost, "/api/v1/bundles", nil, data),
+			wantStatus: http.StatusCreated,
+		},
+		"missing_file": {
+			req:        newUploadRequest(t, http.MethodPost, "/api/v1/bundles", map[string]string{"name": "x"}),
+			wantStatus: http.StatusBadRequest,
+		},
+		"not_a_bundle": {
+			req:        newUploadRequest(t, http.MethodPost, "/api/v1/bundles", nil, []byte("not a zip")),
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		"too_large": {
+			req:        newUploadRequest(t, http.MethodPost, "/api/v1/bundles", nil, make([]byte, 2*1024*1024)),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rec := serve(h, tc.req)
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+
+			if tc.wantStatus != http.StatusCreated {
+				var problem apiProblem
+				decodeJSON(t, rec, "application/problem+json", &problem)
+				require.Equal(t, tc.wantStatus, problem.Status)
+				require.Equal(t, "/api/v1/bundles", problem.Instance)
+				return
+			}
+			var got apiBundle
+			decodeJSON(t, rec, "application/json", &got)
+			require.Equal(t, bundleHash(data), got.Hash)
+			require.Equal(t, "bundle-0.zip", got.OriginalFilename)
+			require.Equal(t, "web-01", got.Info.Host.Hostname)
+		})
+	}
+}
+
+func TestAPI_GetBundle(t *testing.T) {
+	h := newTestHandler(t)
+	hash := uploadAPIBundle(t, h)
+
+	rec := serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/bundles/"+hash, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got apiBundle
+	decodeJSON(t, rec, "application/json", &got)
+	require.Equal(t, hash, got.Hash)
+
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/bundles/unknown", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Bundles can't be listed.
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/bundles", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.NotContains(t, rec.Body.String(), hash)
+}
+
+func TestAPI_Configs(t *testing.T) {
+	h := newTestHandler(t)
+	hash := uploadAPIBundle(t, h)
+
+	rec := serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/bundles/"+hash+"/configs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var configs []apiConfig
+	decodeJSON(t, rec, "application/json", &configs)
+	var filenames []string
+	for _, c := range configs {
+		filenames = append(filenames, c.Filename)
+	}
+	require.Contains(t, filenames, "config/filebeat.yaml")
+
+	tests := map[string]struct {
+		filename    string
+		wantStatus  int
+		wantContent string
+	}{
+		"config":  {filename: "config/filebeat.yaml", wantStatus: http.StatusOK, wantContent: "filebeat.inputs: []\n"},
+		"log":     {filename: testBundleLog, wantStatus: http.StatusNotFound},
+		"unknown": {filename: "config/unknown.yaml", wantStatus: http.StatusNotFound},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			target := "/api/v1/bundles/" + hash + "/configs/content?" + url.Values{"filename": {tc.filename}}.Encode()
+			rec := serve(h, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var got apiConfigContent
+			decodeJSON(t, rec, "application/json", &got)
+			require.Equal(t, tc.filename, got.Filename)
+			require.Equal(t, tc.wantContent, got.Content)
+		})
+	}
+}
+
+func TestAPI_Logs(t *testing.T) {
+	h := newTestHandler(t)
+	hash := uploadAPIBundle(t, h)
+
+	rec := serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/bundles/"+hash+"/logs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got []apiLog
+	decodeJSON(t, rec, "application/json", &got)
+	require.Len(t, got, 1)
+	require.Equal(t, testBundleLog, got[0].Filename)
+	require.Equal(t, "NDJSON", got[0].Type)
+}
+
+func TestAPI_LogQuery(t *testing.T) {
+	h := newTestHandler(t)
+	hash := uploadAPIBundle(t, h)
+
+	tests := map[string]struct {
+		query          url.Values
+		wantStatus     int
+		wantTotal      int
+		wantIndices    []int
+		wantTimestamps []string
+	}{
+		"defaults": {
+			query:       url.Values{},
+			wantStatus:  http.StatusOK,
+			wantTotal:   4,
+			wantIndices: []int{0, 1, 2, 3},
+		},
+		"offset_limit": {
+			query:       url.Values{"offset": {"1"}, "limit": {"2"}},
+			wantStatus:  http.StatusOK,
+			wantTotal:   4,
+			wantIndices: []int{1, 2},
+		},
+		"offset_past_end": {
+			query:       url.Values{"offset": {"10"}},
+			wantStatus:  http.StatusOK,
+			wantTotal:   4,
+			wantIndices: []int{},
+		},
+		"filters": {
+			query:       url.Values{"filter": {"log.level=error", "count>5"}},
+			wantStatus:  http.StatusOK,
+			wantTotal:   2,
+			wantIndices: []int{1, 3},
+		},
+		"tz": {
+			query:          url.Values{"tz": {"Asia/Tokyo"}, "limit": {"1"}},
+			wantStatus:     http.StatusOK,
+			wantTotal:      4,
+			wantIndices:    []int{0},
+			wantTimestamps: []string{"2023-01-04T19:00:00.000+09:00"},
+		},
+		"invalid_offset": {
+			query:      url.Values{"offset": {"-1"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		"invalid_limit": {
+			query:      url.Values{"limit": {"10001"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		"invalid_filter": {
+			query:      url.Values{"filter": {"log.level"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		"invalid_tz": {
+			query:      url.Values{"tz": {"Mars/Olympus"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		"missing_filename": {
+			query:      url.Values{"filename": {""}},
+			wantStatus: http.StatusBadRequest,
+		},
+		"unknown_filename": {
+			query:      url.Values{"filename": {"logs/unknown.ndjson"}},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			query := url.Values{"filename": {testBundleLog}}
+			for k, v := range tc.query {
+				query[k] = v
+			}
+			target := "/api/v1/bundles/" + hash + "/logs/query?" + query.Encode()
+			rec := serve(h, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				var problem apiProblem
+				decodeJSON(t, rec, "application/problem+json", &problem)
+				require.Equal(t, tc.wantStatus, problem.Status)
+				return
+			}
+
+			var got apiLogQuery
+			decodeJSON(t, rec, "application/json", &got)
+			require.Equal(t, "ndjson", got.Parser)
+			require.Equal(t, tc.wantTotal, got.Total)
+			indices := []int{}
+			var timestamps []string
+			for _, line := range got.Lines {
+				indices = append(indices, line.Index)
+				timestamps = append(timestamps, line.Timestamp)
+			}
+			require.Equal(t, tc.wantIndices, indices)
+			if tc.wantTimestamps != nil {
+				require.Equal(t, tc.wantTimestamps, timestamps)
+			}
+		})
+	}
+}
+
+func TestAPI_LogStats(t *testing.T) {
+	h := newTestHandler(t)
+	hash := uploadAPIBundle(t, h)
+
+	target := "/api/v1/bundles/" + hash + "/logs/stats?" + url.Values{"filename": {testBundleLog}}.Encode()
+	rec := serve(h, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var got apiLogStats
+	decodeJSON(t, rec, "application/json", &got)
+	require.Equal(t, testBundleLog, got.Filename)
+	require.Equal(t, "ndjson", got.Parser)
+	require.Equal(t, len(testAPILog), got.Lines)
+	require.Equal(t, []string{"@timestamp", "count", "log.level", "message"}, got.Fields)
+	require.Equal(t, "date", got.FieldTypes["@timestamp"])
+	require.Equal(t, "keyword", got.FieldTypes["log.level"])
+	require.Greater(t, got.MemoryBytes, int64(0))
+}
+
+// TestAPI_OpenAPI checks that the OpenAPI document describes every route of
+// the API, and nothing else.
+func TestAPI_OpenAPI(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(openAPIDocument, &doc))
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(documented)
+
+	r := chi.NewRouter()
+	(&Handler{}).routeAPI(r)
+	var routed []string
+	err := chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routed = append(routed, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(routed)
+
+	require.Equal(t, routed, documented)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/component/skew"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+func (h *Handler) handleGetInspectSkew(w http.ResponseWriter, r *http.Request) {
+	type OutOfOrderInfo struct {
+		Filename string
+		skew.Monotonicity
+	}
+	type EstimateInfo struct {
+		skew.Estimate
+		// Correction is the offset which aligns the file with the agent.
+		Correction time.Duration
+	}
+	type OffsetInfo struct {
+		Filename string
+		Offset   time.Duration
+	}
+	type SkewInfo struct {
+		Hash       string
+		Threshold  time.Duration
+		OutOfOrder []OutOfOrderInfo
+		Estimates  []EstimateInfo
+		Offsets    []OffsetInfo
+		Logs       []logs.Entry
+	}
+
+	fileHash := chi.URLParam(r, "hash")
+
+	logger.Debug().Str("hash", fileHash).Msg("Requesting clock skew")
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+
+	// Setting an offset also renders the skew, so the view is always reloaded
+	// with a GET request.
+	parsed, ok := h.awaitLogs(w, r, jobTargetSkew, url.URL{Path: "/inspect/skew/" + fileHash}, s)
+	if !ok {
+		return
+	}
+
+	info := SkewInfo{
+		Hash:      fileHash,
+		Threshold: skew.Threshold,
+		Logs:      s.Viewer.GetLogs(),
+	}
+
+	var agentEvents []skew.Event
+	componentEvents := map[string][]skew.Event{}
+	for _, log := range parsed[s.Hash] {
+		if log.Err != nil {
+			// The other logs can still be checked.
+			PropsFromContext(r.Context()).AppendError(log.Err)
+			continue
+		}
+		entry, logCtx := log.Entry, log.Context
+		if m := skew.CheckMonotonic(logCtx); m.OutOfOrder > 0 {
+			info.OutOfOrder = append(info.OutOfOrder, OutOfOrderInfo{Filename: entry.Filename, Monotonicity: m})
+		}
+		if entry.Component == logs.ComponentAgent {
+			agentEvents = append(agentEvents, skew.AgentEvents(logCtx)...)
+		} else if binary := skew.Binary(entry.Component); binary != "" {
+			componentEvents[entry.Filename] = skew.ComponentEvents(logCtx, binary)
+		}
+	}
+	for _, entry := range info.Logs {
+		if est, ok := skew.EstimateOffset(entry.Filename, agentEvents, componentEvents[entry.Filename]); ok {
+			info.Estimates = append(info.Estimates, EstimateInfo{Estimate: est, Correction: -est.Offset})
+		}
+	}
+
+	for filename, offset := range s.Offsets() {
+		info.Offsets = append(info.Offsets, OffsetInfo{Filename: filename, Offset: offset})
+	}
+	sort.Slice(info.Offsets, func(i, j int) bool {
+		return info.Offsets[i].Filename < info.Offsets[j].Filename
+	})
+
+	h.renderFragment(w, r, "skew", &info)
+}
+
+// handlePostInspectSkewOffset sets the clock offset applied to a log file in
+// the timeline. An empty offset removes it.
+func (h *Handler) handlePostInspectSkewOffset(w http.ResponseWriter, r *http.Request) {
+	fileHash := chi.URLParam(r, "hash")
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, badRequest(err))
+		return
+	}
+
+	filename := r.PostFormValue("filename")
+	var offset time.Duration
+	if v := r.PostFormValue("offset"); v != "" {
+		var err error
+		if offset, err = time.ParseDuration(v); err != nil {
+			h.renderError(w, r, badRequest(fmt.Errorf("invalid offset: %q", v)))
+			return
+		}
+	}
+
+	s, ok := h.getSession(fileHash)
+	if !ok {
+		h.renderError(w, r, bundleNotFound(fileHash))
+		return
+	}
+	if filename == "" {
+		h.renderError(w, r, badRequest(fmt.Errorf("missing filename")))
+		return
+	}
+
+	logger.Debug().Str("hash", fileHash).Str("filename", filename).Dur("offset", offset).Msg("Setting log clock offset")
+
+	s.SetOffset(filename, offset)
+
+	h.handleGetInspectSkew(w, r)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/taylor-swanson/sawmill/internal/logger"
+	"github.com/taylor-swanson/sawmill/internal/upload"
+)
+
+// The resumable uploads follow the core protocol of tus (https://tus.io) with
+// the creation and termination extensions. When the last chunk of a bundle is
+// received, its session is opened and its hash returned in the
+// Sawmill-Bundle-Hash header.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination"
+	// uploadContentType is the content type of the chunks of an upload.
+	uploadContentType = "application/offset+octet-stream"
+	// bundleHashHeader carries the hash of a bundle once its upload completes.
+	bundleHashHeader = "Sawmill-Bundle-Hash"
+)
+
+// writeUploadError records err with the request and writes it as plain text,
+// which upload clients can show as is.
+func writeUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	PropsFromContext(r.Context()).AppendError(err)
+
+	status := errorStatus(err)
+	http.Error(w, errorMessage(err, status), status)
+}
+
+// parseUploadMetadata parses an Upload-Metadata header, a comma separated list
+// of keys each followed by a space and a base64 encoded value. Values may be
+// empty.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload metadata %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// middlewareTus checks that the protocol version of a request is supported,
+// and adds the version to the response.
+func middlewareTus(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if v := r.Header.Get("Tus-Resumable"); v != "" && v != tusVersion && r.Method != http.MethodOptions {
+			err := fmt.Errorf("unsupported Tus-Resumable version %q", v)
+			PropsFromContext(r.Context()).AppendError(err)
+			w.Header().Set("Tus-Version", tusVersion)
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) handleOptionsUploads(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.uploads.MaxSize(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostUploads starts an upload of a bundle. The request has no body, and
+// gives the size of the bundle in the Upload-Length header and its name in the
+// filename key of Upload-Metadata.
+func (h *Handler) handlePostUploads(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		writeUploadError(w, r, badRequest(fmt.Errorf("invalid Upload-Length: %q", r.Header.Get("Upload-Length"))))
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		writeUploadError(w, r, badRequest(err))
+		return
+	}
+
+	u, err := h.uploads.Create(metadata["filename"], length)
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+
+	logger.Debug().Str("id", u.ID).Str("filename", u.Filename).Int64("length", u.Length).Msg("Starting upload")
+
+	w.Header().Set("Location", "/uploads/"+u.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleHeadUpload returns the number of bytes received, from which an
+// interrupted upload is resumed.
+func (h *Handler) handleHeadUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	w.Header().Set("Cache-Control", "no-store")
+	u, ok := h.uploads.Get(id)
+	if !ok {
+		writeUploadError(w, r, fmt.Errorf("%w: %q", upload.ErrNotFound, id))
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset(), 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlePatchUpload appends a chunk to an upload, starting at the offset given
+// in the Upload-Offset header. The session of the bundle is opened once it has
+// been received completely, unless the same bundle was already uploaded.
+func (h *Handler) handlePatchUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if ct := r.Header.Get("Content-Type"); ct != uploadContentType {
+		writeUploadError(w, r, fmt.Errorf("%w: chunks must have content type %q, got %q", ErrUnsupportedFile, uploadContentType, ct))
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeUploadError(w, r, badRequest(fmt.Errorf("invalid Upload-Offset: %q", r.Header.Get("Upload-Offset"))))
+		return
+	}
+
+	u, ok := h.uploads.Get(id)
+	if !ok {
+		writeUploadError(w, r, fmt.Errorf("%w: %q", upload.ErrNotFound, id))
+		return
+	}
+
+	offset, err = u.Append(offset, r.Body)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+
+	if !u.Done() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if u, err = h.uploads.Finish(id); err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	s, err := h.openSessionFile(u.Path(), u.Filename, u.Hash())
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+
+	logger.Debug().Str("id", id).Str("hash", s.Hash).Msg("Finished upload")
+
+	w.Header().Set(bundleHashHeader, s.Hash)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteUpload cancels an upload.
+func (h *Handler) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.uploads.Remove(id); err != nil {
+		if errors.Is(err, upload.ErrNotFound) {
+			err = fmt.Errorf("%w: %q", err, id)
+		}
+		writeUploadError(w, r, err)
+		return
+	}
+
+	logger.Debug().Str("id", id).Msg("Cancelled upload")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeUploads adds the routes of resumable uploads.
+func (h *Handler) routeUploads(r chi.Router) {
+	r.Use(middlewareTus)
+	r.Options("/", h.handleOptionsUploads)
+	r.Post("/", h.handlePostUploads)
+	r.Head("/{id}", h.handleHeadUpload)
+	r.Patch("/{id}", h.handlePatchUpload)
+	r.Delete("/{id}", h.handleDeleteUpload)
+}
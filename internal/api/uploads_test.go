@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTusRequest returns a request of the tus protocol.
+func newTusRequest(method string, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Tus-Resumable", tusVersion)
+
+	return req
+}
+
+// createUpload starts an upload of length bytes, returning its location.
+func createUpload(t *testing.T, h *Handler, length int) string {
+	t.Helper()
+
+	req := newTusRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", strconv.Itoa(length))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("bundle.zip")))
+	rec := serve(h, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, "0", rec.Header().Get("Upload-Offset"))
+
+	return rec.Header().Get("Location")
+}
+
+// patchUpload sends a chunk of an upload starting at offset.
+func patchUpload(h *Handler, location string, offset int, chunk io.Reader) *httptest.ResponseRecorder {
+	req := newTusRequest(http.MethodPatch, location, chunk)
+	req.Header.Set("Content-Type", uploadContentType)
+	req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+
+	return serve(h, req)
+}
+
+// uploadChunks uploads data in two chunks, returning the response to the last.
+func uploadChunks(t *testing.T, h *Handler, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	location := createUpload(t, h, len(data))
+	half := len(data) / 2
+
+	rec := patchUpload(h, location, 0, bytes.NewReader(data[:half]))
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	require.Equal(t, strconv.Itoa(half), rec.Header().Get("Upload-Offset"))
+	require.Empty(t, rec.Header().Get(bundleHashHeader))
+
+	rec = serve(h, newTusRequest(http.MethodHead, location, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, strconv.Itoa(half), rec.Header().Get("Upload-Offset"))
+	require.Equal(t, strconv.Itoa(len(data)), rec.Header().Get("Upload-Length"))
+
+	return patchUpload(h, location, half, bytes.NewReader(data[half:]))
+}
+
+func TestUploads_Complete(t *testing.T) {
+	h := newTestHandler(t)
+	data := makeTestBundle(t, "web-01", testAPILog...)
+
+	rec := uploadChunks(t, h, data)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	require.Equal(t, strconv.Itoa(len(data)), rec.Header().Get("Upload-Offset"))
+	require.Equal(t, bundleHash(data), rec.Header().Get(bundleHashHeader))
+
+	s, ok := h.getSession(bundleHash(data))
+	require.True(t, ok)
+	require.Equal(t, "bundle.zip", s.OriginalFilename)
+}
+
+func TestUploads_Dedup(t *testing.T) {
+	h := newTestHandler(t)
+	data := makeTestBundle(t, "web-01", testAPILog...)
+
+	first := uploadChunks(t, h, data)
+	second := uploadChunks(t, h, data)
+
+	require.Equal(t, http.StatusNoContent, second.Code, second.Body.String())
+	require.Equal(t, first.Header().Get(bundleHashHeader), second.Header().Get(bundleHashHeader))
+	require.Len(t, h.sessions, 1)
+}
+
+func TestUploads_OffsetMismatch(t *testing.T) {
+	h := newTestHandler(t)
+	location := createUpload(t, h, 10)
+
+	rec := patchUpload(h, location, 0, bytes.NewReader([]byte("abcd")))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	// The chunk is sent again, and the current offset is returned to resume.
+	rec = patchUpload(h, location, 0, bytes.NewReader([]byte("abcd")))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, "4", rec.Header().Get("Upload-Offset"))
+}
+
+func TestUploads_Locked(t *testing.T) {
+	h := newTestHandler(t)
+	location := createUpload(t, h, 10)
+
+	pr, pw := io.Pipe()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- patchUpload(h, location, 0, pr)
+	}()
+	// The first chunk is being read once a write returns.
+	_, err := pw.Write([]byte("abcd"))
+	require.NoError(t, err)
+
+	rec := patchUpload(h, location, 0, bytes.NewReader([]byte("abcd")))
+	require.Equal(t, http.StatusLocked, rec.Code)
+
+	require.NoError(t, pw.Close())
+	rec = <-done
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "4", rec.Header().Get("Upload-Offset"))
+}
+
+func TestUploads_TooLarge(t *testing.T) {
+	h := newTestHandler(t)
+
+	req := newTusRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", strconv.FormatInt(h.uploads.MaxSize()+1, 10))
+	rec := serve(h, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Chunks can't go beyond the length of the upload, and are rejected as a
+	// whole when read at once.
+	location := createUpload(t, h, 4)
+	rec = patchUpload(h, location, 0, bytes.NewReader([]byte("abcdef")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Equal(t, "0", rec.Header().Get("Upload-Offset"))
+}
+
+func TestUploads_Errors(t *testing.T) {
+	h := newTestHandler(t)
+	location := createUpload(t, h, 4)
+
+	tests := map[string]struct {
+		req        func() *http.Request
+		wantStatus int
+	}{
+		"unsupported_version": {
+			req: func() *http.Request {
+				req := newTusRequest(http.MethodHead, location, nil)
+				req.Header.Set("Tus-Resumable", "0.2.2")
+				return req
+			},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		"invalid_length": {
+			req: func() *http.Request {
+				req := newTusRequest(http.MethodPost, "/uploads", nil)
+				req.Header.Set("Upload-Length", "many")
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		"content_type": {
+			req: func() *http.Request {
+				req := newTusRequest(http.MethodPatch, location, bytes.NewReader([]byte("ab")))
+				req.Header.Set("Upload-Offset", "0")
+				return req
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		"unknown_upload": {
+			req: func() *http.Request {
+				return newTusRequest(http.MethodHead, "/uploads/unknown", nil)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		"not_a_bundle": {
+			req: func() *http.Request {
+				req := newTusRequest(http.MethodPatch, location, bytes.NewReader([]byte("abcd")))
+				req.Header.Set("Content-Type", uploadContentType)
+				req.Header.Set("Upload-Offset", "0")
+				return req
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rec := serve(h, tc.req())
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			require.Equal(t, tusVersion, rec.Header().Get("Tus-Resumable"))
+		})
+	}
+}
+
+func TestUploads_Delete(t *testing.T) {
+	h := newTestHandler(t)
+	location := createUpload(t, h, 4)
+
+	rec := serve(h, newTusRequest(http.MethodDelete, location, nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(h, newTusRequest(http.MethodHead, location, nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -11,11 +11,18 @@ import (
 )
 
 type Info struct {
-	BuildTime time.Time
-	Commit    string
-	Snapshot  bool
-	Version   string
-	ID        string
+	BuildTime time.Time `json:"build_time"`
+	Commit    string    `json:"commit"`
+	Snapshot  bool      `json:"snapshot"`
+	Version   string    `json:"version"`
+	ID        string    `json:"id"`
+
+	// The fields below are populated by the viewer from other files in the
+	// bundle, when present.
+	Host        HostInfo          `json:"host"`
+	Fleet       FleetInfo         `json:"fleet"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Profiles    []string          `json:"profiles,omitempty"`
 }
 
 func ParseInfo(r io.Reader) (Info, error) {
@@ -0,0 +1,155 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/taylor-swanson/sawmill/internal/redact"
+)
+
+// HostInfo describes the machine a bundle was collected on.
+type HostInfo struct {
+	Hostname     string   `json:"hostname"`
+	ID           string   `json:"id"`
+	Platform     string   `json:"platform"`
+	Architecture string   `json:"architecture"`
+	IPs          []string `json:"ips"`
+}
+
+// FleetInfo describes the Fleet enrollment of the agent.
+type FleetInfo struct {
+	Enrolled bool     `json:"enrolled"`
+	AgentID  string   `json:"agent_id"`
+	Hosts    []string `json:"hosts"`
+}
+
+// Merge fills the empty fields of h from other.
+func (h *HostInfo) Merge(other HostInfo) {
+	if h.Hostname == "" {
+		h.Hostname = other.Hostname
+	}
+	if h.ID == "" {
+		h.ID = other.ID
+	}
+	if h.Platform == "" {
+		h.Platform = other.Platform
+	}
+	if h.Architecture == "" {
+		h.Architecture = other.Architecture
+	}
+	if len(h.IPs) == 0 {
+		h.IPs = other.IPs
+	}
+}
+
+// ParseLocalConfig reads the Fleet enrollment from the agent's local config.
+func ParseLocalConfig(r io.Reader) (FleetInfo, error) {
+	var raw struct {
+		Fleet struct {
+			Enabled bool     `yaml:"enabled"`
+			Hosts   []string `yaml:"hosts"`
+			Agent   struct {
+				ID string `yaml:"id"`
+			} `yaml:"agent"`
+		} `yaml:"fleet"`
+		Agent struct {
+			ID string `yaml:"id"`
+		} `yaml:"agent"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return FleetInfo{}, fmt.Errorf("unable to decode local config: %w", err)
+	}
+
+	info := FleetInfo{
+		Enrolled: raw.Fleet.Enabled,
+		AgentID:  raw.Fleet.Agent.ID,
+	}
+	if info.AgentID == "" {
+		info.AgentID = raw.Agent.ID
+	}
+	for _, v := range raw.Fleet.Hosts {
+		info.Hosts = append(info.Hosts, redact.Line(v))
+	}
+
+	return info, nil
+}
+
+// ParseVariables reads the host details and environment variables from the
+// variables the agent used to render its policy. Values of sensitive
+// environment variables are redacted.
+func ParseVariables(r io.Reader) (HostInfo, map[string]string, error) {
+	var raw struct {
+		Variables []struct {
+			Host struct {
+				Hostname     string   `yaml:"hostname"`
+				ID           string   `yaml:"id"`
+				Platform     string   `yaml:"platform"`
+				Architecture string   `yaml:"architecture"`
+				IP           []string `yaml:"ip"`
+			} `yaml:"host"`
+			Env map[string]string `yaml:"env"`
+		} `yaml:"variables"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return HostInfo{}, nil, fmt.Errorf("unable to decode variables: %w", err)
+	}
+	if len(raw.Variables) == 0 {
+		return HostInfo{}, nil, nil
+	}
+
+	v := raw.Variables[0]
+	host := HostInfo{
+		Hostname:     v.Host.Hostname,
+		ID:           v.Host.ID,
+		Platform:     v.Host.Platform,
+		Architecture: v.Host.Architecture,
+		IPs:          v.Host.IP,
+	}
+
+	var env map[string]string
+	if len(v.Env) > 0 {
+		env = make(map[string]string, len(v.Env))
+		for k, value := range v.Env {
+			// Values may embed secrets, such as credentials in URLs.
+			if redact.IsSensitiveKey(k) {
+				value = redact.Placeholder
+			} else {
+				value = redact.Line(value)
+			}
+			env[k] = value
+		}
+	}
+
+	return host, env, nil
+}
+
+// ParseBeatMeta reads the host details from the metadata of a Beat.
+func ParseBeatMeta(r io.Reader) (HostInfo, error) {
+	var raw struct {
+		Hostname string `yaml:"hostname"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return HostInfo{}, fmt.Errorf("unable to decode beat meta: %w", err)
+	}
+
+	return HostInfo{Hostname: raw.Hostname}, nil
+}
+
+// IsProfile returns true if filename is a pprof profile.
+func IsProfile(filename string) bool {
+	return strings.HasSuffix(filename, ".pprof") || strings.HasSuffix(filename, ".pprof.gz") || strings.HasPrefix(filename, "pprof/")
+}
+
+// ProfileName returns the name of a profile, such as "goroutine" or "heap".
+func ProfileName(filename string) string {
+	name := path.Base(filename)
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.TrimSuffix(name, ".pprof")
+}
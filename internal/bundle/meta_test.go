@@ -0,0 +1,80 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/redact"
+)
+
+func TestParseLocalConfig(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "local-config.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := ParseLocalConfig(f)
+	require.NoError(t, err)
+
+	require.Equal(t, FleetInfo{
+		Enrolled: true,
+		AgentID:  "4fb4d506-cd63-4231-bce7-f0907cd418e2",
+		Hosts:    []string{"https://" + redact.Placeholder + "@fleet-server:8220"},
+	}, got)
+}
+
+func TestParseVariables(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "variables.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	gotHost, gotEnv, err := ParseVariables(f)
+	require.NoError(t, err)
+
+	require.Equal(t, HostInfo{
+		Hostname:     "web-01",
+		ID:           "8b3b9e3d0f5a4a6b9f0e2a1c3d4e5f60",
+		Platform:     "ubuntu",
+		Architecture: "x86_64",
+		IPs:          []string{"10.0.0.5"},
+	}, gotHost)
+	require.Equal(t, map[string]string{
+		"HOME":        "/root",
+		"ES_PASSWORD": redact.Placeholder,
+		"ES_HOST":     "https://" + redact.Placeholder + "@es01:9200",
+		"ES_HEADERS":  "Authorization: " + redact.Placeholder,
+	}, gotEnv)
+}
+
+func TestParseBeatMeta(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "filebeat-default.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := ParseBeatMeta(f)
+	require.NoError(t, err)
+
+	require.Equal(t, HostInfo{Hostname: "web-01"}, got)
+}
+
+func TestProfileName(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want string
+	}{
+		"v2":         {In: "goroutine.pprof.gz", Want: "goroutine"},
+		"component":  {In: "components/filestream-default/heap.pprof.gz", Want: "heap"},
+		"v1":         {In: "pprof/elastic-agent/goroutine", Want: "goroutine"},
+		"plain_file": {In: "allocs.pprof", Want: "allocs"},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.True(t, IsProfile(tc.In))
+			require.Equal(t, tc.Want, ProfileName(tc.In))
+		})
+	}
+}
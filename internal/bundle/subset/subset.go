@@ -0,0 +1,259 @@
+// Package subset builds new bundles containing a selection of files from an
+// existing bundle.
+package subset
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/redact"
+)
+
+// sniffSize is the number of bytes read to tell text files from binary ones.
+const sniffSize = 512
+
+// ErrRedact is returned when a file can't be parsed to redact it.
+var ErrRedact = errors.New("unable to redact file")
+
+// Options configures which files are included in a sub-bundle and how they are
+// processed.
+type Options struct {
+	// Files are the config and log files to include. The bundle's meta files are
+	// always included.
+	Files []string
+	// From and To restrict log lines to a time range. A zero value leaves that
+	// end of the range unbounded.
+	From time.Time
+	To   time.Time
+	// Redact removes secrets from all included text files. YAML and JSON files
+	// are parsed to redact the values of sensitive keys, other text is redacted
+	// line by line. Binary files, such as profiles, are copied as they are.
+	// Compressed logs are compressed again once processed, except for bzip2
+	// logs, which are copied as they are.
+	Redact bool
+}
+
+// sliced returns true if a time range was set.
+func (o Options) sliced() bool {
+	return !o.From.IsZero() || !o.To.IsZero()
+}
+
+// inRange returns true if t is within the time range.
+func (o Options) inRange(t time.Time) bool {
+	if !o.From.IsZero() && t.Before(o.From) {
+		return false
+	}
+	if !o.To.IsZero() && t.After(o.To) {
+		return false
+	}
+
+	return true
+}
+
+// Write writes a new bundle zip to w containing the viewer's meta files and the
+// selected files, keeping their original paths.
+func Write(w io.Writer, viewer bundle.Viewer, opts Options) error {
+	logFiles := collections.NewSet[string]()
+	for _, v := range viewer.GetLogs() {
+		logFiles.Add(v.Filename)
+	}
+	include := collections.NewSet[string](opts.Files...)
+	include.Add(viewer.MetaFiles()...)
+
+	zw := zip.NewWriter(w)
+
+	err := viewer.Walk("", func(file *zip.File) error {
+		if file.FileInfo().IsDir() || !include.Has(file.Name) {
+			return nil
+		}
+
+		return copyFile(zw, file, logFiles.Has(file.Name), opts)
+	})
+	if err != nil {
+		_ = zw.Close()
+		return err
+	}
+
+	return zw.Close()
+}
+
+func copyFile(zw *zip.Writer, file *zip.File, isLog bool, opts Options) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open %q: %w", file.Name, err)
+	}
+	defer src.Close()
+
+	br := bufio.NewReader(src)
+	head, _ := br.Peek(sniffSize)
+
+	dst, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     file.Name,
+		Method:   zip.Deflate,
+		Modified: file.Modified,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create %q: %w", file.Name, err)
+	}
+
+	sliceLines := isLog && opts.sliced()
+	compression := logs.DetectCompression(head)
+	switch {
+	case isLog && compression != "" && (sliceLines || opts.Redact):
+		err = copyCompressedLog(dst, br, compression, sliceLines, opts)
+	case !isText(head) || (!sliceLines && !opts.Redact):
+		_, err = io.Copy(dst, br)
+	case !isLog:
+		err = redactFile(dst, br, file.Name)
+	default:
+		err = copyLines(dst, br, sliceLines, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to copy %q: %w", file.Name, err)
+	}
+
+	return nil
+}
+
+// redactFile copies src to dst, redacting it as YAML, JSON or text depending on
+// the extension of name.
+func redactFile(dst io.Writer, src io.Reader, name string) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		data, err = redact.YAML(data)
+	case ".json":
+		data, err = redact.JSON(data)
+		data = append(data, '\n')
+	default:
+		data = []byte(redact.Text(string(data)))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedact, err)
+	}
+
+	_, err = dst.Write(data)
+
+	return err
+}
+
+// copyLines copies src to dst line by line, dropping log lines outside the time
+// range when sliceLines is set and redacting lines when requested. Lines without
+// a timestamp follow the decision made for the last line that had one.
+func copyLines(dst io.Writer, src *bufio.Reader, sliceLines bool, opts Options) error {
+	bw := bufio.NewWriter(dst)
+
+	keep := true
+	for {
+		data, readErr := src.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		if len(data) == 0 {
+			break
+		}
+
+		line := string(bytes.TrimSuffix(data, []byte("\n")))
+		if sliceLines {
+			if ts, ok := lineTimestamp([]byte(line)); ok {
+				keep = opts.inRange(ts)
+			}
+		}
+		if keep {
+			if opts.Redact {
+				line = redactLine(line)
+			}
+			if _, err := bw.WriteString(line); err != nil {
+				return err
+			}
+			// The last line keeps its lack of a line ending.
+			if data[len(data)-1] == '\n' {
+				if err := bw.WriteByte('\n'); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	return bw.Flush()
+}
+
+// copyCompressedLog copies the lines of a compressed log like copyLines, then
+// compresses them again. Logs whose compression can only be read, such as
+// bzip2, are copied as they are.
+func copyCompressedLog(dst io.Writer, src *bufio.Reader, compression string, sliceLines bool, opts Options) error {
+	cw, err := logs.Compress(dst, compression)
+	if errors.Is(err, logs.ErrCompressionUnsupported) {
+		_, err = io.Copy(dst, src)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	r, _, err := logs.Decompress(src)
+	if err != nil {
+		_ = cw.Close()
+		return err
+	}
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(sniffSize)
+	if isText(head) {
+		err = copyLines(cw, br, sliceLines, opts)
+	} else {
+		_, err = io.Copy(cw, br)
+	}
+	if err != nil {
+		_ = cw.Close()
+		return err
+	}
+
+	return cw.Close()
+}
+
+// isText returns true if content starting with head is text.
+func isText(head []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+// redactLine redacts a log line, parsing it if it is a JSON object.
+func redactLine(line string) string {
+	if strings.HasPrefix(line, "{") {
+		if data, err := redact.JSON([]byte(line)); err == nil {
+			return string(data)
+		}
+	}
+
+	return redact.Line(line)
+}
+
+// lineTimestamp returns the timestamp of an NDJSON log line.
+func lineTimestamp(data []byte) (time.Time, bool) {
+	var line struct {
+		Timestamp string `json:"@timestamp"`
+	}
+	if err := json.Unmarshal(data, &line); err != nil || line.Timestamp == "" {
+		return time.Time{}, false
+	}
+
+	return logs.ParseDate(line.Timestamp)
+}
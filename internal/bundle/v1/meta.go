@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"archive/zip"
+	"path"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/component/config"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+const pprofDir = "pprof/"
+
+// FindMeta fills in the host, Fleet and profile details of the bundle info.
+// Beats report the hostname in their meta files, and the agent's local config
+// holds its enrollment.
+func FindMeta(viewer *viewer) {
+	err := viewer.Walk(metaDir, func(file *zip.File) error {
+		if file.FileInfo().IsDir() || isVersionFile(file.Name) || path.Ext(file.Name) != ".yaml" {
+			return nil
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		host, err := bundle.ParseBeatMeta(f)
+		if err != nil {
+			logger.Warn().Err(err).Str("filename", file.Name).Msg("Unable to read beat meta")
+			return nil
+		}
+		viewer.info.Host.Merge(host)
+
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting meta")
+	}
+
+	for _, v := range viewer.configs {
+		if v.Type != config.TypeAgent {
+			continue
+		}
+		f, err := viewer.OpenFile(v.Filename)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error getting local config")
+			break
+		}
+		if viewer.info.Fleet, err = bundle.ParseLocalConfig(f); err != nil {
+			logger.Warn().Err(err).Str("filename", v.Filename).Msg("Unable to read local config")
+		}
+		_ = f.Close()
+		break
+	}
+
+	err = viewer.Walk(pprofDir, func(file *zip.File) error {
+		if !file.FileInfo().IsDir() {
+			viewer.info.Profiles = append(viewer.info.Profiles, file.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting profiles")
+	}
+}
+
+func isVersionFile(filename string) bool {
+	for _, v := range versionFilepaths {
+		if v == filename {
+			return true
+		}
+	}
+
+	return false
+}
@@ -16,6 +16,8 @@ import (
 
 const (
 	Name = "v1"
+
+	metaDir = "meta/"
 )
 
 var versionFilepaths = []string{
@@ -71,6 +73,19 @@ func (b *viewer) Walk(dirname string, walkFn func(file *zip.File) error) error {
 	return nil
 }
 
+func (b *viewer) MetaFiles() []string {
+	var files []string
+
+	_ = b.Walk(metaDir, func(file *zip.File) error {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file.Name)
+		}
+		return nil
+	})
+
+	return files
+}
+
 func (b *viewer) GetConfigs() []config.Entry {
 	return b.configs
 }
@@ -102,6 +117,7 @@ func New(filename string) (bundle.Viewer, error) {
 
 	b.configs = FindConfigs(&b)
 	b.logs = FindLogs(&b)
+	FindMeta(&b)
 
 	return &b, nil
 }
@@ -0,0 +1,61 @@
+package v2
+
+import (
+	"archive/zip"
+	"path"
+	"strings"
+
+	"github.com/taylor-swanson/sawmill/internal/component/config"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+// ComputedConfigFile is the fully rendered agent configuration.
+const ComputedConfigFile = "computed-config.yaml"
+
+func GetConfigType(filename string) config.Type {
+	filename = path.Base(filename)
+
+	switch {
+	case filename == ComputedConfigFile:
+		return config.TypeComputed
+	case strings.HasPrefix(filename, "components-"):
+		return config.TypeComponents
+	case strings.HasPrefix(filename, "local-config"):
+		return config.TypeAgent
+	case strings.HasPrefix(filename, "pre-config"):
+		return config.TypeAgentPolicy
+	}
+
+	return config.TypeGeneric
+}
+
+// FindConfigs finds the config files at the top level of the bundle. Other
+// top-level YAML files, such as the agent state, are not configs.
+func FindConfigs(viewer *viewer) []config.Entry {
+	var entries []config.Entry
+
+	err := viewer.Walk("", func(file *zip.File) error {
+		if file.FileInfo().IsDir() || strings.Contains(file.Name, "/") {
+			return nil
+		}
+		if path.Ext(file.Name) != ".yaml" || !isConfigFile(file.Name) {
+			return nil
+		}
+
+		entries = append(entries, config.Entry{
+			Filename: file.Name,
+			Type:     GetConfigType(file.Name),
+		})
+
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting configs")
+	}
+
+	return entries
+}
+
+func isConfigFile(filename string) bool {
+	return strings.HasSuffix(filename, "-config.yaml") || strings.HasPrefix(filename, "components-")
+}
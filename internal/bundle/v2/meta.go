@@ -0,0 +1,41 @@
+package v2
+
+import (
+	"archive/zip"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/logger"
+)
+
+const (
+	localConfigFile = "local-config.yaml"
+	variablesFile   = "variables.yaml"
+)
+
+// FindMeta fills in the host, Fleet, environment and profile details of the
+// bundle info from the top-level files of the bundle.
+func FindMeta(viewer *viewer) {
+	if f, err := viewer.OpenFile(localConfigFile); err == nil {
+		if viewer.info.Fleet, err = bundle.ParseLocalConfig(f); err != nil {
+			logger.Warn().Err(err).Msg("Unable to read local config")
+		}
+		_ = f.Close()
+	}
+
+	if f, err := viewer.OpenFile(variablesFile); err == nil {
+		if viewer.info.Host, viewer.info.Environment, err = bundle.ParseVariables(f); err != nil {
+			logger.Warn().Err(err).Msg("Unable to read variables")
+		}
+		_ = f.Close()
+	}
+
+	err := viewer.Walk("", func(file *zip.File) error {
+		if !file.FileInfo().IsDir() && bundle.IsProfile(file.Name) {
+			viewer.info.Profiles = append(viewer.info.Profiles, file.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting profiles")
+	}
+}
@@ -67,6 +67,10 @@ func (b *viewer) Walk(dirname string, walkFn func(file *zip.File) error) error {
 	return nil
 }
 
+func (b *viewer) MetaFiles() []string {
+	return []string{versionFile}
+}
+
 func (b *viewer) GetConfigs() []config.Entry {
 	return b.configs
 }
@@ -96,6 +100,10 @@ func New(filename string) (bundle.Viewer, error) {
 		return nil, fmt.Errorf("unable to parse bundle info: %w", err)
 	}
 
+	b.configs = FindConfigs(&b)
+	b.logs = FindLogs(&b)
+	FindMeta(&b)
+
 	return &b, nil
 }
 
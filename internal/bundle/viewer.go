@@ -3,6 +3,7 @@ package bundle
 import (
 	"archive/zip"
 	"io/fs"
+	"time"
 
 	"github.com/taylor-swanson/sawmill/internal/component/config"
 	"github.com/taylor-swanson/sawmill/internal/component/logs"
@@ -14,7 +15,25 @@ type Viewer interface {
 	Close() error
 	Walk(dirname string, walkFn func(file *zip.File) error) error
 	OpenFile(filename string) (fs.File, error)
+	// MetaFiles returns the files describing the bundle itself, such as its
+	// version file. These are required for the bundle to be detected.
+	MetaFiles() []string
 
 	GetConfigs() []config.Entry
 	GetLogs() []logs.Entry
 }
+
+// CollectionTime estimates when a bundle was collected, as the latest
+// modification time of its files. A zero time is returned if the bundle has no
+// files with a modification time.
+func CollectionTime(v Viewer) time.Time {
+	var latest time.Time
+	_ = v.Walk("", func(file *zip.File) error {
+		if file.Modified.After(latest) {
+			latest = file.Modified
+		}
+		return nil
+	})
+
+	return latest
+}
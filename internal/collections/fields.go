@@ -7,26 +7,34 @@ import (
 
 type Fields map[string]any
 
+// Get returns the value for key. Nested values may be accessed using dotted keys.
+// Keys which themselves contain dots, such as "log.level", are matched as well,
+// including when nested under another key (e.g. "log.origin.file.name").
 func (f Fields) Get(key string) (any, bool) {
-	rootKey, subKeys, split := strings.Cut(key, ".")
-	if split {
-		switch v := f[rootKey].(type) {
-		case Fields:
-			return v.Get(subKeys)
-		case map[string]any:
-			return Fields(v).Get(subKeys)
-		default:
-			rootKey = key
+	if value, ok := f[key]; ok {
+		if m, isMap := value.(map[string]any); isMap {
+			return Fields(m), true
 		}
+		return value, true
 	}
 
-	value, ok := f[rootKey]
-
-	if m, isMap := value.(map[string]any); ok && isMap {
-		return Fields(m), ok
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+		switch v := f[key[:i]].(type) {
+		case Fields:
+			if value, ok := v.Get(key[i+1:]); ok {
+				return value, true
+			}
+		case map[string]any:
+			if value, ok := Fields(v).Get(key[i+1:]); ok {
+				return value, true
+			}
+		}
 	}
 
-	return value, ok
+	return nil, false
 }
 
 func (f Fields) GetString(key string) (string, bool) {
@@ -122,17 +130,36 @@ func (f Fields) Walk(walkFn WalkFunc) {
 
 func (f Fields) walk(parentKey string, walkFn WalkFunc) {
 	for k, v := range f {
-		if subMap, ok := f[k].(Fields); ok {
-			subMap.walk(k, walkFn)
-		}
 		fullKey := k
 		if parentKey != "" {
 			fullKey = parentKey + "." + fullKey
 		}
+		switch subMap := v.(type) {
+		case Fields:
+			subMap.walk(fullKey, walkFn)
+		case map[string]any:
+			Fields(subMap).walk(fullKey, walkFn)
+		}
 		walkFn(f, fullKey, k, v)
 	}
 }
 
+// Flatten returns a new Fields containing only the leaf values of f, keyed by
+// their full dotted path.
+func (f Fields) Flatten() Fields {
+	flat := Fields{}
+
+	f.Walk(func(_ Fields, fullKey, _ string, value any) {
+		switch value.(type) {
+		case Fields, map[string]any:
+			return
+		}
+		flat[fullKey] = value
+	})
+
+	return flat
+}
+
 // WalkFunc is a callback function that is called for each value of Fields and its
 // sub-nodes. The direct Fields that is associated with key and value is provided. The
 // full path of the key is provided by fullKey.
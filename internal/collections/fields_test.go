@@ -22,3 +22,96 @@ func TestFields_UnmarshalJSON(t *testing.T) {
 	// TODO: Write a real test...
 	require.NoError(t, err)
 }
+
+func TestFields_Get(t *testing.T) {
+	data := []byte(`{
+	"log.level": "info",
+	"log.origin": {
+		"file.name": "instance/beat.go",
+		"file.line": 760
+	},
+	"service": {
+		"name": "filebeat"
+	}
+}`)
+
+	fields := Fields{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	tests := map[string]struct {
+		In     string
+		Want   any
+		WantOk bool
+	}{
+		"dotted_key": {
+			In:     "log.level",
+			Want:   "info",
+			WantOk: true,
+		},
+		"nested": {
+			In:     "service.name",
+			Want:   "filebeat",
+			WantOk: true,
+		},
+		"nested_dotted_key": {
+			In:     "log.origin.file.name",
+			Want:   "instance/beat.go",
+			WantOk: true,
+		},
+		"nested_number": {
+			In:     "log.origin.file.line",
+			Want:   float64(760),
+			WantOk: true,
+		},
+		"map": {
+			In:     "service",
+			Want:   Fields{"name": "filebeat"},
+			WantOk: true,
+		},
+		"missing": {
+			In:     "log.origin.function",
+			WantOk: false,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, ok := fields.Get(tc.In)
+
+			require.Equal(t, tc.WantOk, ok)
+			require.Equal(t, tc.Want, got)
+		})
+	}
+}
+
+func TestFields_Flatten(t *testing.T) {
+	data := []byte(`{
+	"log.level": "info",
+	"log.origin": {
+		"file.name": "instance/beat.go",
+		"file.line": 760
+	},
+	"service": {
+		"name": "filebeat",
+		"node": {
+			"name": "host-1"
+		}
+	},
+	"tags": ["a", "b"]
+}`)
+
+	fields := Fields{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	want := Fields{
+		"log.level":            "info",
+		"log.origin.file.name": "instance/beat.go",
+		"log.origin.file.line": float64(760),
+		"service.name":         "filebeat",
+		"service.node.name":    "host-1",
+		"tags":                 []any{"a", "b"},
+	}
+
+	require.Equal(t, want, fields.Flatten())
+}
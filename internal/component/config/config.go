@@ -10,6 +10,8 @@ const (
 	TypeFilebeat
 	TypeFleetMonitoring
 	TypeMetricbeat
+	TypeComputed
+	TypeComponents
 )
 
 func (t Type) String() string {
@@ -28,6 +30,10 @@ func (t Type) String() string {
 		return "Fleet Monitoring"
 	case TypeMetricbeat:
 		return "Metricbeat"
+	case TypeComputed:
+		return "Computed"
+	case TypeComponents:
+		return "Components"
 	}
 
 	return ""
@@ -0,0 +1,290 @@
+// Package goroutine parses textual goroutine dumps, such as those written by a
+// panic or by a goroutine profile with debug=2.
+package goroutine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LongWait is how long a goroutine must have been blocked to be considered
+// stuck.
+const LongWait = 10 * time.Minute
+
+var (
+	headerRegex = regexp.MustCompile(`^goroutine (\d+) \[([^\]]*)\]:$`)
+	waitRegex   = regexp.MustCompile(`^(\d+) minutes?$`)
+	sourceRegex = regexp.MustCompile(`^\t(.*\.go|\?):(\d+)`)
+)
+
+// Call is a frame of a goroutine's stack.
+type Call struct {
+	Func string
+	File string
+	Line int
+}
+
+// Goroutine is a goroutine in a dump.
+type Goroutine struct {
+	ID int
+	// State is the goroutine's status or wait reason, such as "running" or
+	// "chan receive".
+	State string
+	// Wait is how long the goroutine has been blocked. Go only reports waits
+	// of a minute or more.
+	Wait   time.Duration
+	Locked bool
+	// Stack is ordered from the innermost frame.
+	Stack     []Call
+	CreatedBy *Call
+
+	// lines are the stack lines as they appear in the dump.
+	lines []string
+}
+
+// IsLongBlocked returns true if the goroutine has been blocked for LongWait or
+// more.
+func (g *Goroutine) IsLongBlocked() bool {
+	return g.Wait >= LongWait
+}
+
+// Lines returns the stack lines of the goroutine as they appeared in the dump.
+func (g *Goroutine) Lines() []string {
+	return g.lines
+}
+
+// IsDump returns true if text contains a goroutine dump.
+func IsDump(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if headerRegex.MatchString(strings.TrimRight(line, "\r")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsStackLine returns true if line could be part of a goroutine dump. It is
+// used to find the extent of a dump that was logged one line at a time.
+func IsStackLine(line string) bool {
+	switch {
+	case line == "":
+		return true
+	case headerRegex.MatchString(line), sourceRegex.MatchString(line):
+		return true
+	case strings.HasPrefix(line, "panic: "), strings.HasPrefix(line, "[signal "), strings.HasPrefix(line, "fatal error: "):
+		return true
+	case strings.HasPrefix(line, "created by "), strings.HasPrefix(line, "...additional frames elided..."):
+		return true
+	case strings.HasSuffix(line, ")") && !strings.Contains(line, " "):
+		return true
+	}
+
+	return strings.HasSuffix(line, ")") && strings.Contains(line, "(0x")
+}
+
+// Parse parses the goroutines in a dump. Lines outside of a goroutine, such as
+// a panic message, are ignored.
+func Parse(r io.Reader) ([]*Goroutine, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read goroutine dump: %w", err)
+	}
+
+	return ParseLines(lines), nil
+}
+
+// ParseLines parses the goroutines in the lines of a dump.
+func ParseLines(lines []string) []*Goroutine {
+	var goroutines []*Goroutine
+	var g *Goroutine
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		if m := headerRegex.FindStringSubmatch(line); m != nil {
+			g = newGoroutine(m[1], m[2])
+			goroutines = append(goroutines, g)
+			continue
+		}
+		if g == nil {
+			continue
+		}
+		if line == "" {
+			g = nil
+			continue
+		}
+
+		g.lines = append(g.lines, line)
+		if strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		call := Call{Func: line}
+		if idx := strings.LastIndex(line, "("); idx > 0 && strings.HasSuffix(line, ")") {
+			call.Func = line[:idx]
+		}
+		if i+1 < len(lines) {
+			if m := sourceRegex.FindStringSubmatch(lines[i+1]); m != nil {
+				call.File = m[1]
+				call.Line, _ = strconv.Atoi(m[2])
+			}
+		}
+
+		if strings.HasPrefix(line, "created by ") {
+			call.Func = strings.TrimPrefix(line, "created by ")
+			if idx := strings.Index(call.Func, " in goroutine "); idx > 0 {
+				call.Func = call.Func[:idx]
+			}
+			g.CreatedBy = &call
+			continue
+		}
+		if strings.HasPrefix(line, "...") {
+			continue
+		}
+		g.Stack = append(g.Stack, call)
+	}
+
+	return goroutines
+}
+
+func newGoroutine(id string, status string) *Goroutine {
+	g := &Goroutine{}
+	g.ID, _ = strconv.Atoi(id)
+
+	var state []string
+	for _, part := range strings.Split(status, ", ") {
+		if m := waitRegex.FindStringSubmatch(part); m != nil {
+			minutes, _ := strconv.Atoi(m[1])
+			g.Wait = time.Duration(minutes) * time.Minute
+			continue
+		}
+		if part == "locked to thread" {
+			g.Locked = true
+			continue
+		}
+		state = append(state, part)
+	}
+	g.State = strings.Join(state, ", ")
+
+	return g
+}
+
+// Group is a set of goroutines with the same state and stack.
+type Group struct {
+	State      string
+	Stack      []Call
+	CreatedBy  *Call
+	Goroutines []*Goroutine
+	// MaxWait is the longest wait of the goroutines in the group.
+	MaxWait time.Duration
+}
+
+// Lines returns the stack lines of the first goroutine of the group.
+func (g Group) Lines() []string {
+	return g.Goroutines[0].Lines()
+}
+
+// IsLongBlocked returns true if any goroutine in the group has been blocked
+// for LongWait or more.
+func (g Group) IsLongBlocked() bool {
+	return g.MaxWait >= LongWait
+}
+
+// GroupByStack buckets goroutines by state and stack, largest group first.
+// Calls are compared by function and source line, ignoring their arguments, so
+// goroutines waiting at the same place are grouped even if their arguments
+// differ, while those waiting elsewhere in the same function are not.
+func GroupByStack(goroutines []*Goroutine) []Group {
+	index := map[string]int{}
+	var groups []Group
+
+	for _, g := range goroutines {
+		key := groupKey(g)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{State: g.State, Stack: g.Stack, CreatedBy: g.CreatedBy})
+		}
+		groups[i].Goroutines = append(groups[i].Goroutines, g)
+		if g.Wait > groups[i].MaxWait {
+			groups[i].MaxWait = g.Wait
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Goroutines) > len(groups[j].Goroutines)
+	})
+
+	return groups
+}
+
+func groupKey(g *Goroutine) string {
+	var b strings.Builder
+
+	b.WriteString(g.State)
+	for _, c := range g.Stack {
+		b.WriteByte('|')
+		b.WriteString(c.Func)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(c.Line))
+	}
+	if g.CreatedBy != nil {
+		b.WriteString("|created by ")
+		b.WriteString(g.CreatedBy.Func)
+	}
+
+	return b.String()
+}
+
+// LogDump is a goroutine dump found in a log.
+type LogDump struct {
+	// Index is the index of the log line the dump starts at.
+	Index      int
+	Goroutines []*Goroutine
+}
+
+// FindInMessages finds goroutine dumps in the messages of a log. A dump may be
+// contained in a single message, or logged one line per message, as happens
+// when a component's stderr is captured by the agent.
+func FindInMessages(messages []string) []LogDump {
+	var dumps []LogDump
+
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		if !IsDump(msg) {
+			continue
+		}
+
+		if strings.Contains(msg, "\n") {
+			if goroutines := ParseLines(strings.Split(msg, "\n")); len(goroutines) > 0 {
+				dumps = append(dumps, LogDump{Index: i, Goroutines: goroutines})
+			}
+			continue
+		}
+
+		start := i
+		for i+1 < len(messages) && IsStackLine(messages[i+1]) {
+			i++
+		}
+		if goroutines := ParseLines(messages[start : i+1]); len(goroutines) > 0 {
+			dumps = append(dumps, LogDump{Index: start, Goroutines: goroutines})
+		}
+	}
+
+	return dumps
+}
@@ -0,0 +1,140 @@
+package goroutine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "dump.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := Parse(f)
+	require.NoError(t, err)
+	require.Len(t, got, 5)
+
+	require.Equal(t, 1, got[0].ID)
+	require.Equal(t, "running", got[0].State)
+	require.Equal(t, []Call{{Func: "main.main", File: "/src/main.go", Line: 20}}, got[0].Stack)
+	require.Nil(t, got[0].CreatedBy)
+
+	require.Equal(t, 7, got[1].ID)
+	require.Equal(t, "chan receive", got[1].State)
+	require.Equal(t, 45*time.Minute, got[1].Wait)
+	require.True(t, got[1].IsLongBlocked())
+	require.Equal(t, []Call{{Func: "github.com/example/worker.(*Pool).run", File: "/src/worker/pool.go", Line: 88}}, got[1].Stack)
+	require.Equal(t, &Call{Func: "github.com/example/worker.New", File: "/src/worker/pool.go", Line: 40}, got[1].CreatedBy)
+	require.Len(t, got[1].Lines(), 4)
+
+	require.Equal(t, "syscall", got[4].State)
+	require.Equal(t, 3*time.Minute, got[4].Wait)
+	require.True(t, got[4].Locked)
+	require.False(t, got[4].IsLongBlocked())
+}
+
+func TestGroupByStack(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "dump.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	goroutines, err := Parse(f)
+	require.NoError(t, err)
+
+	got := GroupByStack(goroutines)
+
+	require.Len(t, got, 3)
+	require.Len(t, got[0].Goroutines, 3)
+	require.Equal(t, "chan receive", got[0].State)
+	require.Equal(t, 45*time.Minute, got[0].MaxWait)
+	require.True(t, got[0].IsLongBlocked())
+	require.Equal(t, "running", got[1].State)
+	require.Equal(t, "syscall", got[2].State)
+}
+
+func TestGroupByStack_SourceLines(t *testing.T) {
+	goroutines := ParseLines([]string{
+		"goroutine 1 [select]:",
+		"main.loop(0x1)",
+		"\t/src/main.go:30 +0x1d",
+		"",
+		"goroutine 2 [select]:",
+		"main.loop(0x2)",
+		"\t/src/main.go:30 +0x1d",
+		"",
+		"goroutine 3 [select]:",
+		"main.loop(0x1)",
+		"\t/src/main.go:42 +0x1d",
+	})
+	require.Len(t, goroutines, 3)
+
+	got := GroupByStack(goroutines)
+
+	require.Len(t, got, 2)
+	require.Len(t, got[0].Goroutines, 2)
+	require.Equal(t, 30, got[0].Stack[0].Line)
+	require.Len(t, got[1].Goroutines, 1)
+	require.Equal(t, 42, got[1].Stack[0].Line)
+}
+
+func TestFindInMessages(t *testing.T) {
+	dump, err := os.ReadFile(filepath.Join("testdata", "dump.txt"))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		In        []string
+		WantIndex []int
+		WantCount []int
+	}{
+		"single_message": {
+			In:        []string{"starting", "panic: boom\n\n" + string(dump), "restarting"},
+			WantIndex: []int{1},
+			WantCount: []int{5},
+		},
+		"line_per_message": {
+			In: []string{
+				"starting",
+				"panic: boom",
+				"",
+				"goroutine 1 [running]:",
+				"main.main()",
+				"\t/src/main.go:20 +0x1d",
+				"exit status 2",
+				"goroutine 3 [select]:",
+				"main.loop()",
+				"\t/src/main.go:30 +0x1d",
+			},
+			WantIndex: []int{3, 7},
+			WantCount: []int{1, 1},
+		},
+		"none": {
+			In: []string{"starting", "stopping"},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got := FindInMessages(tc.In)
+
+			var gotIndex, gotCount []int
+			for _, v := range got {
+				gotIndex = append(gotIndex, v.Index)
+				gotCount = append(gotCount, len(v.Goroutines))
+			}
+
+			require.Equal(t, tc.WantIndex, gotIndex)
+			require.Equal(t, tc.WantCount, gotCount)
+		})
+	}
+}
+
+func TestIsDump(t *testing.T) {
+	require.True(t, IsDump("panic: boom\n\ngoroutine 1 [running]:\nmain.main()"))
+	require.False(t, IsDump(strings.Repeat("goroutine leak suspected\n", 3)))
+}
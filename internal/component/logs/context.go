@@ -2,23 +2,33 @@ package logs
 
 import (
 	"sort"
+	"time"
 
 	"github.com/taylor-swanson/sawmill/internal/collections"
 )
 
+// TimestampField is the field holding the timestamp of a log line.
+const TimestampField = "@timestamp"
+
 type ContextConfig struct {
 	SkipKeys []string
+	// Index builds an inverted index of string fields when the context is
+	// analyzed, which speeds up text filters at the cost of memory.
+	Index bool
 }
 
 func DefaultContextConfig() ContextConfig {
 	return ContextConfig{
 		SkipKeys: []string{"@timestamp", "message"},
+		Index:    true,
 	}
 }
 
 type Stats struct {
-	Lines  int      `json:"lines"`
-	Fields []string `json:"fields"`
+	Lines       int      `json:"lines"`
+	Fields      []string `json:"fields"`
+	IndexTokens int      `json:"index_tokens"`
+	IndexBytes  int64    `json:"index_bytes"`
 }
 
 type Context struct {
@@ -26,14 +36,25 @@ type Context struct {
 	keys      collections.Set[string]
 	skipKeys  collections.Set[string]
 	keyValues map[string]collections.Set[string]
+	types     map[string]FieldType
+	times     []time.Time
+	useIndex  bool
+	index     *Index
+	size      int64
 }
 
 func (c *Context) AddLine(line collections.Fields) {
 	c.lines = append(c.lines, line)
 }
 
+// AddLineRaw adds a plain text line. A timestamp at the start of the line is
+// added as TimestampField.
 func (c *Context) AddLineRaw(line string) {
-	c.lines = append(c.lines, collections.Fields{"message": line})
+	fields := collections.Fields{"message": line}
+	if ts, ok := leadingTimestamp(line); ok {
+		fields[TimestampField] = ts
+	}
+	c.lines = append(c.lines, fields)
 }
 
 func (c *Context) Fields() []string {
@@ -43,20 +64,46 @@ func (c *Context) Fields() []string {
 	return fields
 }
 
+// Analyze collects the fields and values of all lines. Nested fields are
+// flattened into their full dotted keys.
 func (c *Context) Analyze() {
-	for _, line := range c.lines {
-		for k, v := range line {
+	if c.useIndex {
+		c.index = newIndex()
+	}
+	// Each line is a map referenced by the slice of lines.
+	c.size = int64(len(c.lines)) * (timeSize + pointerSize + mapHeaderSize)
+	c.times = make([]time.Time, len(c.lines))
+
+	for i, line := range c.lines {
+		if value, ok := line.GetString(TimestampField); ok {
+			c.times[i], _ = ParseDate(value)
+		}
+		for k, v := range line.Flatten() {
+			c.size += int64(mapEntrySize + stringHeaderSize + len(k))
+			if value, ok := v.(string); ok {
+				c.size += int64(len(value))
+			}
 			c.keys.Add(k)
+			c.addType(k, v)
+			if value, ok := v.(string); ok && c.index != nil {
+				c.index.add(i, k, value)
+			}
 			if c.skipKeys.Has(k) {
 				continue
 			}
 
 			switch value := v.(type) {
 			case string:
-				if _, ok := c.keyValues[k]; !ok {
-					c.keyValues[k] = collections.NewSet[string](value)
-				} else {
-					c.keyValues[k].Add(value)
+				// Values share their bytes with the lines.
+				values, ok := c.keyValues[k]
+				if !ok {
+					values = collections.NewSet[string]()
+					c.keyValues[k] = values
+					c.size += int64(mapEntrySize + stringHeaderSize + len(k) + mapHeaderSize)
+				}
+				if !values.Has(value) {
+					values.Add(value)
+					c.size += int64(mapEntrySize + stringHeaderSize)
 				}
 			default:
 				// Unsupported value not indexed.
@@ -64,6 +111,62 @@ func (c *Context) Analyze() {
 			}
 		}
 	}
+
+	if c.index != nil {
+		c.index.finish()
+	}
+}
+
+// addType records the type of a field, preferring its ECS type and otherwise
+// inferring it from the values seen so far.
+func (c *Context) addType(field string, value any) {
+	if t, ok := SchemaType(field); ok {
+		c.types[field] = t
+		return
+	}
+
+	// Once a field holds mixed values it remains a keyword.
+	if t := c.types[field]; t != FieldTypeKeyword {
+		c.types[field] = mergeTypes(t, InferType(value))
+	}
+}
+
+// FieldType returns the type of a field, or FieldTypeUnknown if the field is
+// not present in any analyzed line.
+func (c *Context) FieldType(field string) FieldType {
+	return c.types[field]
+}
+
+// TypedFilters converts text filters to filters of the types of their fields,
+// so that numbers, dates, booleans and IP addresses are compared by value.
+// Relative times, such as "now-15m", are relative to now.
+func (c *Context) TypedFilters(now time.Time, filters ...*TextFilter) ([]Filter, error) {
+	typed := make([]Filter, 0, len(filters))
+	for _, f := range filters {
+		tf, err := NewFilter(c.FieldType(f.Field), f, now)
+		if err != nil {
+			return nil, err
+		}
+		typed = append(typed, tf)
+	}
+
+	return typed, nil
+}
+
+// Index returns the inverted index of the context, or nil if it was not built.
+func (c *Context) Index() *Index {
+	return c.index
+}
+
+// MemoryUsage returns an estimate of the memory used by the analyzed lines, the
+// distinct values of their fields and the index, in bytes.
+func (c *Context) MemoryUsage() int64 {
+	size := c.size
+	if c.index != nil {
+		size += c.index.MemoryUsage()
+	}
+
+	return size
 }
 
 func (c *Context) Lines() int {
@@ -74,18 +177,30 @@ func (c *Context) Stats() Stats {
 	fields := c.keys.Values()
 	sort.Strings(fields)
 
-	return Stats{
+	stats := Stats{
 		Lines:  len(c.lines),
 		Fields: fields,
 	}
+	if c.index != nil {
+		stats.IndexTokens = c.index.Tokens()
+		stats.IndexBytes = c.index.MemoryUsage()
+	}
+
+	return stats
 }
 
 func (c *Context) Reset() {
 	c.lines = nil
+	c.index = nil
+	c.times = nil
+	c.size = 0
 	c.keys.Clear()
 	for k := range c.keyValues {
 		delete(c.keyValues, k)
 	}
+	for k := range c.types {
+		delete(c.types, k)
+	}
 }
 
 func (c *Context) View(indices ...int) []collections.Fields {
@@ -102,7 +217,7 @@ func (c *Context) View(indices ...int) []collections.Fields {
 }
 
 func (c *Context) ViewRange(start, end int) []collections.Fields {
-	if start > end || start < 0 || end >= len(c.lines) {
+	if start > end || start < 0 || end > len(c.lines) {
 		return nil
 	}
 
@@ -113,10 +228,125 @@ func (c *Context) ViewAll() []collections.Fields {
 	return c.lines
 }
 
+// Filter returns the indices of all lines matching every filter. If no filters
+// are given, all indices are returned.
+//
+// When the context is indexed, text filters narrow down the lines to check
+// before the filters are applied.
+func (c *Context) Filter(filters ...Filter) []int {
+	candidates, ok := c.candidates(filters)
+	if !ok {
+		indices := make([]int, 0, len(c.lines))
+		for i, line := range c.lines {
+			if MatchAll(line, filters...) {
+				indices = append(indices, i)
+			}
+		}
+		return indices
+	}
+
+	indices := make([]int, 0, len(candidates))
+	for _, i := range candidates {
+		if MatchAll(c.lines[i], filters...) {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// candidates returns the lines which may match all filters, using the index.
+// False is returned if the index cannot help with any of the filters.
+func (c *Context) candidates(filters []Filter) ([]int, bool) {
+	if c.index == nil {
+		return nil, false
+	}
+
+	var result []int
+	found := false
+	for _, filter := range filters {
+		tf, ok := filter.(*TextFilter)
+		if !ok {
+			continue
+		}
+		lines, ok := c.index.candidates(tf)
+		if !ok {
+			continue
+		}
+		if !found {
+			result, found = lines, true
+		} else {
+			result = intersect(result, lines)
+		}
+	}
+
+	return result, found
+}
+
+// ViewSurrounding returns up to before lines preceding and after lines following
+// the line at index, including the line itself. Filters are not applied. The index
+// of the first returned line is also returned.
+func (c *Context) ViewSurrounding(index, before, after int) (int, []collections.Fields) {
+	if index < 0 || index >= len(c.lines) {
+		return 0, nil
+	}
+
+	start := index - before
+	if start < 0 {
+		start = 0
+	}
+	end := index + after + 1
+	if end > len(c.lines) {
+		end = len(c.lines)
+	}
+
+	return start, c.ViewRange(start, end)
+}
+
+// ViewTimeRange returns the indices of all lines with a timestamp between from
+// and to, inclusive. Lines without a valid timestamp are skipped.
+func (c *Context) ViewTimeRange(from, to time.Time) []int {
+	var indices []int
+
+	for i := range c.lines {
+		ts, ok := c.Timestamp(i)
+		if !ok {
+			continue
+		}
+		if ts.Before(from) || ts.After(to) {
+			continue
+		}
+		indices = append(indices, i)
+	}
+
+	return indices
+}
+
+// Timestamp returns the parsed timestamp of the line at index. Timestamps are
+// parsed once by Analyze, in any of the layouts accepted for date fields.
+func (c *Context) Timestamp(index int) (time.Time, bool) {
+	if index < 0 || index >= len(c.lines) {
+		return time.Time{}, false
+	}
+	if index < len(c.times) {
+		return c.times[index], !c.times[index].IsZero()
+	}
+
+	// Lines added since the last Analyze.
+	value, ok := c.lines[index].GetString(TimestampField)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return ParseDate(value)
+}
+
 func NewContext(config ContextConfig) *Context {
 	return &Context{
 		skipKeys:  collections.NewSet[string](config.SkipKeys...),
 		keys:      collections.NewSet[string](),
 		keyValues: map[string]collections.Set[string]{},
+		types:     map[string]FieldType{},
+		useIndex:  config.Index,
 	}
 }
@@ -0,0 +1,115 @@
+package logs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+func newTestContext(n int) *Context {
+	c := NewContext(DefaultContextConfig())
+
+	base, _ := time.Parse(time.RFC3339, "2023-01-04T22:53:00Z")
+	for i := 0; i < n; i++ {
+		c.AddLine(collections.Fields{
+			"@timestamp": base.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano),
+			"id":         float64(i),
+		})
+	}
+	c.Analyze()
+
+	return c
+}
+
+func TestContext_ViewSurrounding(t *testing.T) {
+	tests := map[string]struct {
+		InIndex   int
+		InBefore  int
+		InAfter   int
+		WantStart int
+		WantIDs   []float64
+	}{
+		"middle": {
+			InIndex:   5,
+			InBefore:  2,
+			InAfter:   2,
+			WantStart: 3,
+			WantIDs:   []float64{3, 4, 5, 6, 7},
+		},
+		"clamp_start": {
+			InIndex:   1,
+			InBefore:  3,
+			InAfter:   1,
+			WantStart: 0,
+			WantIDs:   []float64{0, 1, 2},
+		},
+		"clamp_end": {
+			InIndex:   9,
+			InBefore:  1,
+			InAfter:   5,
+			WantStart: 8,
+			WantIDs:   []float64{8, 9},
+		},
+		"out_of_range": {
+			InIndex:   10,
+			InBefore:  1,
+			InAfter:   1,
+			WantStart: 0,
+			WantIDs:   nil,
+		},
+	}
+
+	c := newTestContext(10)
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			start, lines := c.ViewSurrounding(tc.InIndex, tc.InBefore, tc.InAfter)
+
+			var gotIDs []float64
+			for _, line := range lines {
+				id, _ := line.GetNumber("id")
+				gotIDs = append(gotIDs, id)
+			}
+
+			require.Equal(t, tc.WantStart, start)
+			require.Equal(t, tc.WantIDs, gotIDs)
+		})
+	}
+}
+
+func TestContext_ViewTimeRange(t *testing.T) {
+	c := newTestContext(10)
+
+	center, ok := c.Timestamp(4)
+	require.True(t, ok)
+
+	got := c.ViewTimeRange(center.Add(-2*time.Second), center.Add(2*time.Second))
+	require.Equal(t, []int{2, 3, 4, 5, 6}, got)
+}
+
+func TestContext_MemoryUsage(t *testing.T) {
+	const n = 100
+
+	analyze := func(skipKeys ...string) *Context {
+		c := NewContext(ContextConfig{SkipKeys: skipKeys})
+		for i := 0; i < n; i++ {
+			c.AddLine(collections.Fields{"user": fmt.Sprintf("user-%03d", i)})
+		}
+		c.Analyze()
+
+		return c
+	}
+
+	skipped := analyze("user")
+	collected := analyze()
+
+	// Every line is a map, even if its fields are skipped.
+	require.GreaterOrEqual(t, skipped.MemoryUsage(), int64(n*(pointerSize+mapHeaderSize)))
+	// The distinct values of fields are kept as well.
+	require.GreaterOrEqual(t, collected.MemoryUsage()-skipped.MemoryUsage(), int64(n*(mapEntrySize+stringHeaderSize)))
+}
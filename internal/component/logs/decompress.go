@@ -0,0 +1,141 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of log files.
+const (
+	CompressionGzip  = "gzip"
+	CompressionZstd  = "zstd"
+	CompressionBzip2 = "bzip2"
+)
+
+// ErrCompressionUnsupported is returned by Compress for compressions which can
+// only be read.
+var ErrCompressionUnsupported = errors.New("compression unsupported")
+
+type compression struct {
+	name      string
+	ext       string
+	magic     []byte
+	newReader func(r io.Reader) (io.Reader, error)
+	// newWriter is nil if the compression can only be read.
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+var compressions = []compression{
+	{
+		name:  CompressionGzip,
+		ext:   ".gz",
+		magic: []byte{0x1f, 0x8b},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	},
+	{
+		name:  CompressionZstd,
+		ext:   ".zst",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			// A single goroutine decodes synchronously, so the decoder does not
+			// need to be closed.
+			return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		},
+	},
+	{
+		name:  CompressionBzip2,
+		ext:   ".bz2",
+		magic: []byte("BZh"),
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+}
+
+// Decompress detects the compression of r by its magic bytes, returning a
+// reader of the uncompressed content and the name of the compression. Content
+// which is not compressed is returned as is, with an empty compression.
+func Decompress(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+
+	c, ok := detectCompression(head)
+	if !ok {
+		return br, "", nil
+	}
+	cr, err := c.newReader(br)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read %s: %w", c.name, err)
+	}
+
+	return cr, c.name, nil
+}
+
+// DetectCompression returns the name of the compression of content starting
+// with head, or an empty string if it is not compressed.
+func DetectCompression(head []byte) string {
+	c, _ := detectCompression(head)
+
+	return c.name
+}
+
+func detectCompression(head []byte) (compression, bool) {
+	for _, c := range compressions {
+		if bytes.HasPrefix(head, c.magic) {
+			return c, true
+		}
+	}
+
+	return compression{}, false
+}
+
+// Compress returns a writer compressing to w with the named compression, which
+// must be closed to flush it. ErrCompressionUnsupported is returned for
+// compressions which can only be read, such as bzip2.
+func Compress(w io.Writer, name string) (io.WriteCloser, error) {
+	for _, c := range compressions {
+		if c.name != name {
+			continue
+		}
+		if c.newWriter == nil {
+			return nil, fmt.Errorf("%w: %s", ErrCompressionUnsupported, name)
+		}
+
+		return c.newWriter(w)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrCompressionUnsupported, name)
+}
+
+// TrimCompressionExt removes the extension of a compressed file, such that
+// "elastic-agent.ndjson.gz" becomes "elastic-agent.ndjson".
+func TrimCompressionExt(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, c := range compressions {
+		if ext == c.ext {
+			return filename[:len(filename)-len(ext)]
+		}
+	}
+
+	return filename
+}
@@ -0,0 +1,119 @@
+package logs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompress(t *testing.T) {
+	tests := map[string]struct {
+		filename        string
+		wantCompression string
+	}{
+		"plain": {filename: "rotated.ndjson"},
+		"gzip":  {filename: "rotated.ndjson.gz", wantCompression: CompressionGzip},
+		"zstd":  {filename: "rotated.ndjson.zst", wantCompression: CompressionZstd},
+		"bzip2": {filename: "rotated.ndjson.bz2", wantCompression: CompressionBzip2},
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "rotated.ndjson"))
+	require.NoError(t, err)
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tc.filename))
+			require.NoError(t, err)
+			defer f.Close()
+
+			r, compression, err := Decompress(f)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantCompression, compression)
+
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	want := "first line\nsecond line\n"
+
+	for _, name := range []string{CompressionGzip, CompressionZstd} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := Compress(&buf, name)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, want)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			require.Equal(t, name, DetectCompression(buf.Bytes()))
+			r, compression, err := Decompress(&buf)
+			require.NoError(t, err)
+			require.Equal(t, name, compression)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, want, string(got))
+		})
+	}
+
+	_, err := Compress(io.Discard, CompressionBzip2)
+	require.ErrorIs(t, err, ErrCompressionUnsupported)
+}
+
+func TestCompressedFilenames(t *testing.T) {
+	tests := map[string]struct {
+		filename      string
+		wantTrimmed   string
+		wantType      Type
+		wantComponent Component
+	}{
+		"plain": {
+			filename:      "logs/elastic-agent-20230104.ndjson",
+			wantTrimmed:   "logs/elastic-agent-20230104.ndjson",
+			wantType:      TypeNDJSON,
+			wantComponent: ComponentAgent,
+		},
+		"gzip": {
+			filename:      "logs/elastic-agent-20230104.ndjson.gz",
+			wantTrimmed:   "logs/elastic-agent-20230104.ndjson",
+			wantType:      TypeNDJSON,
+			wantComponent: ComponentAgent,
+		},
+		"zstd": {
+			filename:      "logs/filebeat-20230104.ndjson.zst",
+			wantTrimmed:   "logs/filebeat-20230104.ndjson",
+			wantType:      TypeNDJSON,
+			wantComponent: ComponentFilebeat,
+		},
+		"bzip2_upper": {
+			filename:      "logs/metricbeat.ndjson.BZ2",
+			wantTrimmed:   "logs/metricbeat.ndjson",
+			wantType:      TypeNDJSON,
+			wantComponent: ComponentMetricbeat,
+		},
+		"compressed_text": {
+			filename:      "logs/other.log.gz",
+			wantTrimmed:   "logs/other.log",
+			wantType:      TypeGeneric,
+			wantComponent: ComponentGeneric,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wantTrimmed, TrimCompressionExt(tc.filename))
+			require.Equal(t, tc.wantType, GetType(tc.filename))
+			require.Equal(t, tc.wantComponent, GetComponent(tc.filename))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+type csvWriter struct {
+	cw      *csv.Writer
+	columns []string
+	record  []string
+}
+
+func (w *csvWriter) Write(line collections.Fields) error {
+	flat := line.Flatten()
+	for i, col := range w.columns {
+		w.record[i], _ = formatValue(flat[col])
+	}
+
+	return w.cw.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.cw.Flush()
+
+	return w.cw.Error()
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, fmt.Errorf("unable to write csv header: %w", err)
+	}
+
+	return &csvWriter{
+		cw:      cw,
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}, nil
+}
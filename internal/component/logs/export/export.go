@@ -0,0 +1,98 @@
+// Package export provides writers for exporting log lines to other formats.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+const (
+	FormatNDJSON  = "ndjson"
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+var (
+	ErrFormatUnsupported = errors.New("export format unsupported")
+	ErrColumnsRequired   = errors.New("export format requires columns")
+)
+
+// Writer writes log lines to an export format. Close must be called once all
+// lines have been written.
+type Writer interface {
+	Write(line collections.Fields) error
+	Close() error
+}
+
+// NewWriter creates a Writer for format which writes to w. Columns selects the
+// flattened fields to write for tabular formats (CSV and Parquet). NDJSON
+// always writes complete lines.
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatCSV:
+		if len(columns) == 0 {
+			return nil, ErrColumnsRequired
+		}
+		return newCSVWriter(w, columns)
+	case FormatParquet:
+		if len(columns) == 0 {
+			return nil, ErrColumnsRequired
+		}
+		return newParquetWriter(w, columns), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrFormatUnsupported, format)
+}
+
+// ContentType returns the MIME type for format.
+func ContentType(format string) string {
+	switch strings.ToLower(format) {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv"
+	}
+
+	return "application/octet-stream"
+}
+
+// Export writes each line of logCtx matching all filters to w, in order, and
+// returns the number of lines written. Lines are found with the index of logCtx
+// when it has one. The writer is not closed.
+func Export(w Writer, logCtx *logs.Context, filters ...logs.Filter) (int, error) {
+	count := 0
+
+	for _, line := range logCtx.View(logCtx.Filter(filters...)...) {
+		if err := w.Write(line); err != nil {
+			return count, fmt.Errorf("unable to write line: %w", err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// formatValue formats a flattened value for tabular formats. Ok is false if
+// the value is missing.
+func formatValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return jsonString(v), true
+	}
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+func newTestContext() *logs.Context {
+	c := logs.NewContext(logs.DefaultContextConfig())
+	c.AddLine(collections.Fields{
+		"@timestamp": "2023-01-04T22:53:00Z",
+		"log.level":  "info",
+		"log":        map[string]any{"origin": map[string]any{"file.line": float64(12)}},
+		"message":    "starting",
+	})
+	c.AddLine(collections.Fields{
+		"@timestamp": "2023-01-04T22:53:01Z",
+		"log.level":  "error",
+		"message":    "connection refused, retrying",
+	})
+	c.AddLineRaw("not json")
+	c.Analyze()
+
+	return c
+}
+
+func TestExport(t *testing.T) {
+	errorFilter := &logs.TextFilter{Operator: logs.FilterOpEquals, Field: "log.level", Value: "error"}
+
+	tests := map[string]struct {
+		InFormat  string
+		InColumns []string
+		InFilters []logs.Filter
+		Want      string
+		WantCount int
+	}{
+		"ndjson": {
+			InFormat:  FormatNDJSON,
+			InFilters: []logs.Filter{errorFilter},
+			Want:      `{"@timestamp":"2023-01-04T22:53:01Z","log.level":"error","message":"connection refused, retrying"}` + "\n",
+			WantCount: 1,
+		},
+		"ndjson_includes": {
+			InFormat:  FormatNDJSON,
+			InFilters: []logs.Filter{&logs.TextFilter{Operator: logs.FilterOpIncludes, Field: "message", Value: "RETRY"}},
+			Want:      `{"@timestamp":"2023-01-04T22:53:01Z","log.level":"error","message":"connection refused, retrying"}` + "\n",
+			WantCount: 1,
+		},
+		"csv": {
+			InFormat:  FormatCSV,
+			InColumns: []string{"log.level", "log.origin.file.line", "message"},
+			Want: "log.level,log.origin.file.line,message\n" +
+				"info,12,starting\n" +
+				"error,,\"connection refused, retrying\"\n" +
+				",,not json\n",
+			WantCount: 3,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			w, err := NewWriter(tc.InFormat, buf, tc.InColumns)
+			require.NoError(t, err)
+
+			count, err := Export(w, newTestContext(), tc.InFilters...)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			require.Equal(t, tc.WantCount, count)
+			require.Equal(t, tc.Want, buf.String())
+		})
+	}
+}
+
+func TestExport_Parquet(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(FormatParquet, buf, []string{"message", "log.level"})
+	require.NoError(t, err)
+
+	count, err := Export(w, newTestContext())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, 3, count)
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), f.NumRows())
+
+	rows := make([]parquet.Row, 3)
+	n, _ := f.RowGroups()[0].Rows().ReadRows(rows)
+	require.Equal(t, 3, n)
+
+	// Columns are ordered by name: log.level, message.
+	require.Equal(t, "info", rows[0][0].String())
+	require.Equal(t, "starting", rows[0][1].String())
+	require.True(t, rows[2][0].IsNull())
+	require.Equal(t, "not json", rows[2][1].String())
+}
+
+func TestNewWriter_Errors(t *testing.T) {
+	_, err := NewWriter("xml", &bytes.Buffer{}, nil)
+	require.ErrorIs(t, err, ErrFormatUnsupported)
+
+	_, err = NewWriter(FormatCSV, &bytes.Buffer{}, nil)
+	require.ErrorIs(t, err, ErrColumnsRequired)
+}
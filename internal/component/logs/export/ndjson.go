@@ -0,0 +1,39 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+type ndjsonWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(line collections.Fields) error {
+	return w.enc.Encode(line)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.bw.Flush()
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	return &ndjsonWriter{bw: bw, enc: enc}
+}
+
+func jsonString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+// parquetRowGroupSize is the number of rows buffered before a row group is
+// written out, bounding memory use for large exports.
+const parquetRowGroupSize = 10000
+
+type parquetWriter struct {
+	pw *parquet.Writer
+	// columns holds the field for each leaf column, in schema order.
+	columns []string
+	row     parquet.Row
+	rows    []parquet.Row
+}
+
+func (w *parquetWriter) Write(line collections.Fields) error {
+	flat := line.Flatten()
+
+	w.row = w.row[:0]
+	for i, col := range w.columns {
+		if v, ok := formatValue(flat[col]); ok {
+			w.row = append(w.row, parquet.ByteArrayValue([]byte(v)).Level(0, 1, i))
+		} else {
+			w.row = append(w.row, parquet.NullValue().Level(0, 0, i))
+		}
+	}
+	w.rows[0] = w.row
+
+	_, err := w.pw.WriteRows(w.rows)
+
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.pw.Close()
+}
+
+// newParquetWriter creates a writer storing every column as an optional string.
+func newParquetWriter(w io.Writer, columns []string) *parquetWriter {
+	group := make(parquet.Group, len(columns))
+	for _, col := range columns {
+		group[col] = parquet.Optional(parquet.String())
+	}
+	schema := parquet.NewSchema("log", group)
+
+	// Group fields are ordered by name, so the leaf columns must be mapped back.
+	leaves := schema.Columns()
+	ordered := make([]string, 0, len(leaves))
+	for _, path := range leaves {
+		ordered = append(ordered, path[0])
+	}
+
+	return &parquetWriter{
+		pw:      parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		columns: ordered,
+		row:     make(parquet.Row, 0, len(columns)),
+		rows:    make([]parquet.Row, 1),
+	}
+}
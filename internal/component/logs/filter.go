@@ -3,6 +3,9 @@ package logs
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -54,7 +57,7 @@ func (o *FilterOp) UnmarshalJSON(b []byte) error {
 	case "EQUALS":
 		*o = FilterOpEquals
 	case "NOT_EQUALS":
-		*o = FilterOpEquals
+		*o = FilterOpNotEquals
 	case "GREATER_THAN":
 		*o = FilterOpGreaterThan
 	case "LESS_THAN":
@@ -98,12 +101,58 @@ func (o *FilterOp) MarshalJSON() ([]byte, error) {
 		return nil, fmt.Errorf("unable to marshal FilterOp, unknown operator: %d", *o)
 	}
 
-	return []byte(out), nil
+	return json.Marshal(out)
 }
 
 type Filter interface {
 	Filter(line collections.Fields) bool
-	ValidOps() FilterOp
+	ValidOps() []FilterOp
+}
+
+// MatchAll returns true if line matches every filter.
+func MatchAll(line collections.Fields, filters ...Filter) bool {
+	for _, f := range filters {
+		if !f.Filter(line) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseTextFilter parses a text filter expression of the form field=value
+// (equals), field!=value (not equals), field~value (includes), field!~value
+// (excludes), field>value (greater than) or field<value (less than).
+// Comparisons only apply once converted by NewFilter to the field's type.
+func ParseTextFilter(expr string) (*TextFilter, error) {
+	ops := []struct {
+		token string
+		op    FilterOp
+	}{
+		// Two character tokens must be checked first.
+		{"!=", FilterOpNotEquals},
+		{"!~", FilterOpExcludes},
+		{"=", FilterOpEquals},
+		{"~", FilterOpIncludes},
+		{">", FilterOpGreaterThan},
+		{"<", FilterOpLessThan},
+	}
+
+	idx := strings.IndexAny(expr, "!=~<>")
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid filter expression %q", expr)
+	}
+	for _, v := range ops {
+		if strings.HasPrefix(expr[idx:], v.token) {
+			return &TextFilter{
+				Operator: v.op,
+				Field:    expr[:idx],
+				Value:    expr[idx+len(v.token):],
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid filter expression %q", expr)
 }
 
 type TextFilter struct {
@@ -159,13 +208,13 @@ func (f *NumberFilter) Filter(line collections.Fields) bool {
 	case FilterOpNotEquals:
 		return value != f.Value
 	case FilterOpGreaterThan:
-		return f.Value > value
+		return value > f.Value
 	case FilterOpLessThan:
-		return f.Value < value
+		return value < f.Value
 	case FilterOpBetween:
 		return f.Value <= value && value <= f.Value2
 	case FilterOpNotBetween:
-		return f.Value < value || value > f.Value2
+		return value < f.Value || value > f.Value2
 	}
 
 	return false
@@ -180,34 +229,39 @@ type TimeFilter struct {
 	Field    string    `json:"field"`
 	Value    time.Time `json:"value"`
 	Value2   time.Time `json:"value2,omitempty"`
-	Format   string    `json:"format"`
+	// Format is the layout of the field's values. If empty, any of the
+	// layouts accepted for ECS date fields are parsed.
+	Format string `json:"format"`
 }
 
 func (f *TimeFilter) Filter(line collections.Fields) bool {
+	var value time.Time
+	var ok bool
 	if f.Format == "" {
-		f.Format = time.RFC3339Nano
+		var s string
+		if s, ok = line.GetString(f.Field); ok {
+			value, ok = ParseDate(s)
+		}
+	} else {
+		value, ok = line.GetTime(f.Field, f.Format)
 	}
-	value, ok := line.GetTime(f.Field, f.Format)
 	if !ok {
 		return false
 	}
 
 	switch f.Operator {
 	case FilterOpEquals:
-		return value == f.Value
+		return value.Equal(f.Value)
 	case FilterOpNotEquals:
-		return value != f.Value
+		return !value.Equal(f.Value)
 	case FilterOpGreaterThan:
-		return f.Value.After(value)
+		return value.After(f.Value)
 	case FilterOpLessThan:
-		return f.Value.Before(value)
+		return value.Before(f.Value)
 	case FilterOpBetween:
-		if value == f.Value || value == f.Value2 {
-			return true
-		}
-		return f.Value.After(value) && f.Value2.Before(value)
+		return !value.Before(f.Value) && !value.After(f.Value2)
 	case FilterOpNotBetween:
-		return f.Value.Before(value) || f.Value2.After(value)
+		return value.Before(f.Value) || value.After(f.Value2)
 	}
 
 	return false
@@ -242,3 +296,75 @@ func (f *BoolFilter) Filter(line collections.Fields) bool {
 func (f *BoolFilter) ValidOps() []FilterOp {
 	return []FilterOp{FilterOpEquals, FilterOpNotEquals}
 }
+
+// IPFilter matches IP address fields, either against a single address or,
+// when Value is in CIDR notation, against a network.
+type IPFilter struct {
+	Operator FilterOp `json:"operator"`
+	Field    string   `json:"field"`
+	Value    string   `json:"value"`
+}
+
+func (f *IPFilter) Filter(line collections.Fields) bool {
+	value, ok := line.GetString(f.Field)
+	if !ok {
+		return false
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+
+	var match bool
+	if _, network, err := net.ParseCIDR(f.Value); err == nil {
+		match = network.Contains(ip)
+	} else {
+		match = ip.Equal(net.ParseIP(f.Value))
+	}
+
+	switch f.Operator {
+	case FilterOpEquals:
+		return match
+	case FilterOpNotEquals:
+		return !match
+	}
+
+	return false
+}
+
+func (f *IPFilter) ValidOps() []FilterOp {
+	return []FilterOp{FilterOpEquals, FilterOpNotEquals}
+}
+
+// NewFilter converts a text filter to a filter comparing values of the given
+// field type. Dates may be relative to now, see ParseTimeExpr. Values which
+// cannot be parsed as the field type, and operators the typed filter does not
+// support, such as includes, are left as a text filter. An error is returned if
+// no filter supports the operator.
+func NewFilter(t FieldType, f *TextFilter, now time.Time) (Filter, error) {
+	var typed Filter
+	switch t {
+	case FieldTypeDate:
+		if value, err := ParseTimeExpr(f.Value, now); err == nil {
+			typed = &TimeFilter{Operator: f.Operator, Field: f.Field, Value: value}
+		}
+	case FieldTypeLong, FieldTypeFloat:
+		if value, err := strconv.ParseFloat(f.Value, 64); err == nil {
+			typed = &NumberFilter{Operator: f.Operator, Field: f.Field, Value: value}
+		}
+	case FieldTypeBoolean:
+		if value, err := strconv.ParseBool(f.Value); err == nil {
+			typed = &BoolFilter{Operator: f.Operator, Field: f.Field, Value: value}
+		}
+	case FieldTypeIP:
+		typed = &IPFilter{Operator: f.Operator, Field: f.Field, Value: f.Value}
+	}
+
+	for _, filter := range []Filter{typed, f} {
+		if filter != nil && slices.Contains(filter.ValidOps(), f.Operator) {
+			return filter, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %q is not supported for %s field %q", f.Operator.String(), t, f.Field)
+}
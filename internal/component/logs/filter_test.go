@@ -0,0 +1,178 @@
+package logs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+func TestFilterOp_JSON(t *testing.T) {
+	ops := []FilterOp{
+		FilterOpEquals,
+		FilterOpNotEquals,
+		FilterOpGreaterThan,
+		FilterOpLessThan,
+		FilterOpIncludes,
+		FilterOpExcludes,
+		FilterOpBetween,
+		FilterOpNotBetween,
+	}
+
+	for _, op := range ops {
+		op := op
+		t.Run(op.String(), func(t *testing.T) {
+			data, err := json.Marshal(&op)
+			require.NoError(t, err)
+
+			var got FilterOp
+			require.NoError(t, json.Unmarshal(data, &got))
+			require.Equal(t, op, got)
+		})
+	}
+}
+
+func TestContext_Filter(t *testing.T) {
+	c := NewContext(DefaultContextConfig())
+	c.AddLine(collections.Fields{"log.level": "info", "message": "starting"})
+	c.AddLine(collections.Fields{"log.level": "error", "message": "connection refused"})
+	c.AddLine(collections.Fields{"log.level": "info", "message": "connection established"})
+	c.Analyze()
+
+	tests := map[string]struct {
+		In   []Filter
+		Want []int
+	}{
+		"none": {
+			Want: []int{0, 1, 2},
+		},
+		"equals": {
+			In:   []Filter{&TextFilter{Operator: FilterOpEquals, Field: "log.level", Value: "INFO"}},
+			Want: []int{0, 2},
+		},
+		"combined": {
+			In: []Filter{
+				&TextFilter{Operator: FilterOpNotEquals, Field: "log.level", Value: "error"},
+				&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "connection"},
+			},
+			Want: []int{2},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.Want, c.Filter(tc.In...))
+		})
+	}
+}
+
+func TestParseTextFilter(t *testing.T) {
+	tests := map[string]struct {
+		In      string
+		Want    *TextFilter
+		WantErr bool
+	}{
+		"equals":       {In: "log.level=error", Want: &TextFilter{Operator: FilterOpEquals, Field: "log.level", Value: "error"}},
+		"not_equals":   {In: "log.level!=error", Want: &TextFilter{Operator: FilterOpNotEquals, Field: "log.level", Value: "error"}},
+		"includes":     {In: "message~a=b", Want: &TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "a=b"}},
+		"excludes":     {In: "message!~refused", Want: &TextFilter{Operator: FilterOpExcludes, Field: "message", Value: "refused"}},
+		"greater_than": {In: "event.duration>1000", Want: &TextFilter{Operator: FilterOpGreaterThan, Field: "event.duration", Value: "1000"}},
+		"less_than":    {In: "@timestamp<2023-01-05T00:00:00Z", Want: &TextFilter{Operator: FilterOpLessThan, Field: "@timestamp", Value: "2023-01-05T00:00:00Z"}},
+		"no_field":     {In: "=error", WantErr: true},
+		"no_operator":  {In: "error", WantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTextFilter(tc.In)
+			if tc.WantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Want, got)
+		})
+	}
+}
+
+func TestContext_Filter_Typed(t *testing.T) {
+	c := NewContext(DefaultContextConfig())
+	c.AddLine(collections.Fields{"@timestamp": "2023-01-05T10:00:00.000Z", "event": collections.Fields{"duration": float64(500)}, "source": collections.Fields{"ip": "10.0.0.1"}, "ok": true})
+	c.AddLine(collections.Fields{"@timestamp": "2023-01-05T11:00:00.000Z", "event": collections.Fields{"duration": float64(1500)}, "source": collections.Fields{"ip": "192.168.1.5"}, "ok": false})
+	c.AddLine(collections.Fields{"@timestamp": "2023-01-05T12:00:00.000Z", "event": collections.Fields{"duration": float64(2500)}, "source": collections.Fields{"ip": "10.1.2.3"}, "ok": true})
+	c.Analyze()
+	now := time.Date(2023, 1, 5, 12, 30, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		In      []*TextFilter
+		Want    []int
+		WantErr bool
+	}{
+		"number_equals": {
+			In:   []*TextFilter{{Operator: FilterOpEquals, Field: "event.duration", Value: "1500"}},
+			Want: []int{1},
+		},
+		"number_greater_than": {
+			In:   []*TextFilter{{Operator: FilterOpGreaterThan, Field: "event.duration", Value: "1000"}},
+			Want: []int{1, 2},
+		},
+		"number_less_than": {
+			In:   []*TextFilter{{Operator: FilterOpLessThan, Field: "event.duration", Value: "1000"}},
+			Want: []int{0},
+		},
+		"date_greater_than": {
+			In:   []*TextFilter{{Operator: FilterOpGreaterThan, Field: "@timestamp", Value: "2023-01-05T10:30:00Z"}},
+			Want: []int{1, 2},
+		},
+		"date_less_than": {
+			In:   []*TextFilter{{Operator: FilterOpLessThan, Field: "@timestamp", Value: "2023-01-05T11:00:00Z"}},
+			Want: []int{0},
+		},
+		"date_relative": {
+			In:   []*TextFilter{{Operator: FilterOpGreaterThan, Field: "@timestamp", Value: "now-1h"}},
+			Want: []int{2},
+		},
+		"date_equals_other_layout": {
+			In:   []*TextFilter{{Operator: FilterOpEquals, Field: "@timestamp", Value: "2023-01-05T11:00:00Z"}},
+			Want: []int{1},
+		},
+		"ip_cidr": {
+			In:   []*TextFilter{{Operator: FilterOpEquals, Field: "source.ip", Value: "10.0.0.0/8"}},
+			Want: []int{0, 2},
+		},
+		"ip_not_equals": {
+			In:   []*TextFilter{{Operator: FilterOpNotEquals, Field: "source.ip", Value: "10.0.0.1"}},
+			Want: []int{1, 2},
+		},
+		"ip_includes": {
+			In:   []*TextFilter{{Operator: FilterOpIncludes, Field: "source.ip", Value: "168"}},
+			Want: []int{1},
+		},
+		"bool": {
+			In:   []*TextFilter{{Operator: FilterOpEquals, Field: "ok", Value: "false"}},
+			Want: []int{1},
+		},
+		"keyword_greater_than": {
+			In:      []*TextFilter{{Operator: FilterOpGreaterThan, Field: "source.ip", Value: "10.0.0.1"}},
+			WantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			filters, err := c.TypedFilters(now, tc.In...)
+			if tc.WantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Want, c.Filter(filters...))
+		})
+	}
+}
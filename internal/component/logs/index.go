@@ -0,0 +1,247 @@
+package logs
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Memory accounting estimates, in bytes, for the structures of an index and
+// the analyzed lines.
+const (
+	stringHeaderSize = 16
+	sliceHeaderSize  = 24
+	mapEntrySize     = 48
+	mapHeaderSize    = 48
+	pointerSize      = 8
+	postingSize      = 8
+	timeSize         = 24
+)
+
+// Index is an inverted index of the tokens in the string fields of a log. Each
+// field has its own posting lists, mapping a token to the sorted indices of the
+// lines containing it in that field.
+type Index struct {
+	fields map[string]*fieldIndex
+}
+
+type fieldIndex struct {
+	postings map[string][]int
+	// tokens are the sorted keys of postings, used for prefix and substring
+	// lookups.
+	tokens []string
+}
+
+func newIndex() *Index {
+	return &Index{fields: map[string]*fieldIndex{}}
+}
+
+// add indexes the tokens of a string field of the line at index. Lines must be
+// added in order.
+func (x *Index) add(index int, field string, value string) {
+	fi, ok := x.fields[field]
+	if !ok {
+		fi = &fieldIndex{postings: map[string][]int{}}
+		x.fields[field] = fi
+	}
+
+	for _, token := range tokenize(value) {
+		posting := fi.postings[token]
+		if len(posting) > 0 && posting[len(posting)-1] == index {
+			continue
+		}
+		fi.postings[token] = append(posting, index)
+	}
+}
+
+// finish prepares the index for queries once all lines are added.
+func (x *Index) finish() {
+	for _, fi := range x.fields {
+		fi.tokens = make([]string, 0, len(fi.postings))
+		for token := range fi.postings {
+			fi.tokens = append(fi.tokens, token)
+		}
+		sort.Strings(fi.tokens)
+	}
+}
+
+// Term returns the indices of lines where field contains term as a whole token.
+// Matching is case-insensitive.
+func (x *Index) Term(field string, term string) []int {
+	fi, ok := x.fields[field]
+	if !ok {
+		return nil
+	}
+
+	return fi.postings[strings.ToLower(term)]
+}
+
+// Prefix returns the indices of lines where field contains a token starting
+// with prefix. Matching is case-insensitive.
+func (x *Index) Prefix(field string, prefix string) []int {
+	fi, ok := x.fields[field]
+	if !ok {
+		return nil
+	}
+
+	return fi.union(fi.withPrefix(strings.ToLower(prefix)))
+}
+
+// Tokens returns the number of distinct tokens in the index, across all fields.
+func (x *Index) Tokens() int {
+	count := 0
+	for _, fi := range x.fields {
+		count += len(fi.postings)
+	}
+
+	return count
+}
+
+// MemoryUsage returns an estimate of the memory used by the index, in bytes.
+func (x *Index) MemoryUsage() int64 {
+	var size int64
+
+	for field, fi := range x.fields {
+		size += int64(mapEntrySize + stringHeaderSize + len(field))
+		for token, posting := range fi.postings {
+			size += int64(mapEntrySize + stringHeaderSize + len(token))
+			size += int64(sliceHeaderSize + cap(posting)*postingSize)
+		}
+		size += int64(sliceHeaderSize + cap(fi.tokens)*stringHeaderSize)
+	}
+
+	return size
+}
+
+// candidates returns the sorted indices of lines which may match a text filter,
+// or false if the index cannot narrow down the lines for the filter. Candidates
+// must still be checked against the filter.
+func (x *Index) candidates(f *TextFilter) ([]int, bool) {
+	if f.Operator != FilterOpIncludes && f.Operator != FilterOpEquals {
+		return nil, false
+	}
+	tokens := tokenize(f.Value)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	fi, ok := x.fields[f.Field]
+	if !ok {
+		return nil, true
+	}
+
+	var result []int
+	for i, token := range tokens {
+		var posting []int
+
+		switch {
+		case f.Operator == FilterOpEquals:
+			// The whole value matches, so every token is a whole token.
+			posting = fi.postings[token]
+		case len(tokens) == 1:
+			posting = fi.union(fi.containing(token))
+		case i == 0:
+			// The first token may be the end of a longer token.
+			posting = fi.union(fi.withSuffix(token))
+		case i == len(tokens)-1:
+			// The last token may be the start of a longer token.
+			posting = fi.union(fi.withPrefix(token))
+		default:
+			posting = fi.postings[token]
+		}
+
+		if i == 0 {
+			result = posting
+		} else {
+			result = intersect(result, posting)
+		}
+		if len(result) == 0 {
+			return nil, true
+		}
+	}
+
+	return result, true
+}
+
+func (fi *fieldIndex) withPrefix(prefix string) []string {
+	start := sort.SearchStrings(fi.tokens, prefix)
+	end := start
+	for end < len(fi.tokens) && strings.HasPrefix(fi.tokens[end], prefix) {
+		end++
+	}
+
+	return fi.tokens[start:end]
+}
+
+func (fi *fieldIndex) withSuffix(suffix string) []string {
+	var tokens []string
+	for _, token := range fi.tokens {
+		if strings.HasSuffix(token, suffix) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func (fi *fieldIndex) containing(substr string) []string {
+	var tokens []string
+	for _, token := range fi.tokens {
+		if strings.Contains(token, substr) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// union merges the posting lists of tokens into one sorted list.
+func (fi *fieldIndex) union(tokens []string) []int {
+	switch len(tokens) {
+	case 0:
+		return nil
+	case 1:
+		return fi.postings[tokens[0]]
+	}
+
+	var merged []int
+	for _, token := range tokens {
+		merged = append(merged, fi.postings[token]...)
+	}
+	sort.Ints(merged)
+
+	deduped := merged[:0]
+	for i, v := range merged {
+		if i == 0 || v != merged[i-1] {
+			deduped = append(deduped, v)
+		}
+	}
+
+	return deduped
+}
+
+// intersect returns the values found in both sorted lists.
+func intersect(a, b []int) []int {
+	var result []int
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+
+	return result
+}
+
+// tokenize splits a value into lowercase tokens of letters and digits.
+func tokenize(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,135 @@
+package logs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+var testIndexLines = []collections.Fields{
+	{"message": "Connection refused by remote host", "log": collections.Fields{"level": "error"}},
+	{"message": "connection-refused while dialing", "log.level": "warn"},
+	{"message": "Reconnection succeeded", "log.level": "info"},
+	{"message": "Unit state changed to FAILED", "log.level": "error", "component": map[string]any{"id": "filestream-default"}},
+	{"message": "refused"},
+	{"message": "Non-zero metrics in the last 30s", "log.level": "info"},
+	{"message": 42},
+}
+
+func newIndexTestContexts() (*Context, *Context) {
+	indexed := NewContext(ContextConfig{Index: true})
+	scanned := NewContext(ContextConfig{Index: false})
+	for _, line := range testIndexLines {
+		indexed.AddLine(line)
+		scanned.AddLine(line)
+	}
+	indexed.Analyze()
+	scanned.Analyze()
+
+	return indexed, scanned
+}
+
+func TestContext_Filter_Index(t *testing.T) {
+	tests := map[string][]Filter{
+		"single_token":         {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "refused"}},
+		"substring":            {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "nnect"}},
+		"phrase":               {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "nection refused by"}},
+		"phrase_punctuation":   {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "on-ref"}},
+		"case":                 {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "FAILED"}},
+		"equals":               {&TextFilter{Operator: FilterOpEquals, Field: "log.level", Value: "ERROR"}},
+		"nested_field":         {&TextFilter{Operator: FilterOpEquals, Field: "component.id", Value: "filestream-default"}},
+		"missing_field":        {&TextFilter{Operator: FilterOpIncludes, Field: "error.message", Value: "refused"}},
+		"no_tokens":            {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "-"}},
+		"excludes":             {&TextFilter{Operator: FilterOpExcludes, Field: "message", Value: "refused"}},
+		"combined":             {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "refused"}, &TextFilter{Operator: FilterOpEquals, Field: "log.level", Value: "error"}},
+		"combined_not_indexed": {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "connection"}, &TextFilter{Operator: FilterOpNotEquals, Field: "log.level", Value: "error"}},
+	}
+
+	indexed, scanned := newIndexTestContexts()
+
+	for name, filters := range tests {
+		filters := filters
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, scanned.Filter(filters...), indexed.Filter(filters...))
+		})
+	}
+}
+
+func TestIndex_TermPrefix(t *testing.T) {
+	indexed, _ := newIndexTestContexts()
+	index := indexed.Index()
+	require.NotNil(t, index)
+
+	require.Equal(t, []int{0, 1, 4}, index.Term("message", "Refused"))
+	require.Nil(t, index.Term("message", "refuse"))
+	require.Equal(t, []int{0, 1, 4}, index.Prefix("message", "refuse"))
+	require.Equal(t, []int{0, 1}, index.Prefix("message", "conn"))
+	require.Equal(t, []int{0, 3}, index.Term("log.level", "error"))
+
+	stats := indexed.Stats()
+	require.Equal(t, index.Tokens(), stats.IndexTokens)
+	require.Greater(t, stats.IndexBytes, int64(0))
+}
+
+// newBenchmarkContext builds a large agent log with a mix of common and rare
+// messages.
+func newBenchmarkContext(lines int, index bool) *Context {
+	messages := []string{
+		"Non-zero metrics in the last 30s",
+		"Unit state changed filestream-default-filestream-system-1 (HEALTHY->HEALTHY): Healthy",
+		"Checkin request to fleet-server succeeded",
+		"Harvester started for paths: [/var/log/syslog]",
+		"File is inactive. Closing because close_inactive of 5m0s reached.",
+	}
+
+	c := NewContext(ContextConfig{SkipKeys: []string{"@timestamp", "message"}, Index: index})
+	for i := 0; i < lines; i++ {
+		msg := messages[i%len(messages)]
+		level := "info"
+		if i%997 == 0 {
+			msg = fmt.Sprintf("Failed to connect to backoff(elasticsearch(https://es%d:9200)): connection refused", i%7)
+			level = "error"
+		}
+		c.AddLine(collections.Fields{
+			"@timestamp": "2023-01-04T22:53:22.000Z",
+			"log.level":  level,
+			"log.logger": "publisher_pipeline_output",
+			"message":    msg,
+			"component":  map[string]any{"id": "filestream-default"},
+		})
+	}
+	c.Analyze()
+
+	return c
+}
+
+func BenchmarkContext_Filter(b *testing.B) {
+	const lines = 200000
+
+	filters := map[string][]Filter{
+		"rare_term":   {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "connection refused"}},
+		"common_term": {&TextFilter{Operator: FilterOpIncludes, Field: "message", Value: "metrics"}},
+		"equals":      {&TextFilter{Operator: FilterOpEquals, Field: "log.level", Value: "error"}},
+	}
+
+	for _, index := range []bool{false, true} {
+		c := newBenchmarkContext(lines, index)
+		mode := "scan"
+		if index {
+			mode = "index"
+			b.Logf("index: %d tokens, %d bytes", c.Stats().IndexTokens, c.Stats().IndexBytes)
+		}
+
+		for name, f := range filters {
+			f := f
+			b.Run(mode+"/"+name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					c.Filter(f...)
+				}
+			})
+		}
+	}
+}
@@ -3,6 +3,8 @@ package logs
 import (
 	"path/filepath"
 	"strings"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
 )
 
 type Type int
@@ -47,6 +49,43 @@ func (c Component) String() string {
 	return ""
 }
 
+// DefaultColumns returns the fields shown by default when viewing logs of
+// this component. The segment of a line is only present in rotated logs.
+func (c Component) DefaultColumns() []string {
+	switch c {
+	case ComponentAgent:
+		return []string{TimestampField, "log.level", "component.id", "message", SegmentField}
+	case ComponentFilebeat, ComponentMetricbeat:
+		return []string{TimestampField, "log.level", "log.logger", "message", SegmentField}
+	}
+
+	return []string{TimestampField, "log.level", "message", SegmentField}
+}
+
+// ResolveColumns returns the fields to show for a log file of a component.
+// Chosen columns take precedence over the component's default columns. Only
+// fields present in the file are kept; if none are, all fields are returned.
+func ResolveColumns(chosen []string, component Component, fields []string) []string {
+	present := collections.NewSet[string](fields...)
+
+	candidates := chosen
+	if len(candidates) == 0 {
+		candidates = component.DefaultColumns()
+	}
+
+	columns := make([]string, 0, len(candidates))
+	for _, field := range candidates {
+		if present.Has(field) {
+			columns = append(columns, field)
+		}
+	}
+	if len(columns) == 0 {
+		return fields
+	}
+
+	return columns
+}
+
 type Entry struct {
 	Filename  string
 	Type      Type
@@ -54,7 +93,7 @@ type Entry struct {
 }
 
 func GetType(filename string) Type {
-	ext := filepath.Ext(filename)
+	ext := filepath.Ext(TrimCompressionExt(filename))
 
 	switch ext {
 	case ".ndjson":
@@ -65,7 +104,7 @@ func GetType(filename string) Type {
 }
 
 func GetComponent(filename string) Component {
-	filename = filepath.Base(filename)
+	filename = filepath.Base(TrimCompressionExt(filename))
 
 	if strings.HasPrefix(filename, "elastic-agent") {
 		return ComponentAgent
@@ -2,6 +2,7 @@ package ndjson
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,6 +26,7 @@ func (p *ndjson) Parse(r io.Reader) (*logs.Context, error) {
 		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
 			pCtx.AddLineRaw(scanner.Text())
 		} else {
+			logs.Coerce(line)
 			pCtx.AddLine(line)
 		}
 		lineNum += 1
@@ -38,6 +40,26 @@ func (p *ndjson) Parse(r io.Reader) (*logs.Context, error) {
 	return pCtx, nil
 }
 
+// sniff reports whether the first line of head is a JSON object. A line cut
+// off by the end of head only needs to start like one.
+func sniff(head []byte) bool {
+	for len(head) > 0 {
+		line, rest, found := bytes.Cut(head, []byte{'\n'})
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			head = rest
+			continue
+		}
+		if line[0] != '{' {
+			return false
+		}
+
+		return !found || json.Valid(line)
+	}
+
+	return false
+}
+
 func New() logs.Parser {
 	return &ndjson{}
 }
@@ -46,6 +68,9 @@ func init() {
 	if err := logs.Register(Name, New); err != nil {
 		panic(fmt.Errorf("unable to register ndjson logs: %w", err))
 	}
+	if err := logs.RegisterSniffer(Name, sniff); err != nil {
+		panic(fmt.Errorf("unable to register ndjson sniffer: %w", err))
+	}
 	for _, ext := range []string{".ndjson", ".json"} {
 		if err := logs.RegisterFileType(ext, Name); err != nil {
 			panic(fmt.Errorf("unable to register ndjson file extension: %q: %w", ext, err))
@@ -3,6 +3,7 @@ package logs
 import (
 	"errors"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -17,6 +18,7 @@ var (
 var (
 	registry          = map[string]FactoryFunc{}
 	registryFileTypes = map[string]string{}
+	registrySniffers  = map[string]SniffFunc{}
 	registryMu        sync.RWMutex
 )
 
@@ -68,6 +70,30 @@ func GetNameForFileType(fileType string) (string, error) {
 	return registryFileTypes[fileType], nil
 }
 
+// HasParser reports whether a parser is registered under name.
+func HasParser(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	_, exists := registry[name]
+
+	return exists
+}
+
+// Parsers returns the sorted names of all registered parsers.
+func Parsers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 func NewParser(name string) (Parser, error) {
 	registryMu.RLock()
 	defer registryMu.RUnlock()
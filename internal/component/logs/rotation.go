@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+// SegmentField is the field added to the lines of a rotated log, holding the
+// filename of the segment the line was read from.
+const SegmentField = "log.segment"
+
+// rotatedPattern matches the names of rotated log files, such as
+// "elastic-agent-20230104.ndjson" or "elastic-agent-20230104-1.ndjson.gz",
+// capturing the base name, date, sequence number and extension.
+var rotatedPattern = regexp.MustCompile(`^(.+)-(\d{8})(?:-(\d+))?(\.[^-]+)$`)
+
+// RotatedLog is a logical log made of the segments of a rotated log file.
+type RotatedLog struct {
+	// Name is the name of the logical log, with a wildcard in place of the
+	// date and sequence number of the segments.
+	Name      string
+	Type      Type
+	Component Component
+	// Segments are the filenames of the segments, ordered by the date and
+	// sequence number in their names. The names don't tell reliably which
+	// segment is older, so MergeSegments orders them by their timestamps.
+	Segments []string
+}
+
+type segment struct {
+	filename string
+	date     string
+	seq      int
+}
+
+// GroupRotated groups the segments of rotated log files by their directory,
+// base name and extension. Only logs with more than one segment are returned,
+// sorted by name.
+func GroupRotated(entries []Entry) []RotatedLog {
+	groups := map[string][]segment{}
+	for _, entry := range entries {
+		dir, file := path.Split(entry.Filename)
+		m := rotatedPattern.FindStringSubmatch(TrimCompressionExt(file))
+		if m == nil {
+			continue
+		}
+		seq := 0
+		if m[3] != "" {
+			seq, _ = strconv.Atoi(m[3])
+		}
+
+		name := dir + m[1] + "-*" + m[4]
+		groups[name] = append(groups[name], segment{filename: entry.Filename, date: m[2], seq: seq})
+	}
+
+	var rotated []RotatedLog
+	for name, segments := range groups {
+		if len(segments) < 2 {
+			continue
+		}
+		sort.Slice(segments, func(i, j int) bool {
+			if segments[i].date != segments[j].date {
+				return segments[i].date < segments[j].date
+			}
+			return segments[i].seq < segments[j].seq
+		})
+
+		log := RotatedLog{
+			Name:      name,
+			Type:      GetType(name),
+			Component: GetComponent(segments[0].filename),
+		}
+		for _, s := range segments {
+			log.Segments = append(log.Segments, s.filename)
+		}
+		rotated = append(rotated, log)
+	}
+	sort.Slice(rotated, func(i, j int) bool {
+		return rotated[i].Name < rotated[j].Name
+	})
+
+	return rotated
+}
+
+// FindRotated returns the rotated log with the given name.
+func FindRotated(entries []Entry, name string) (RotatedLog, bool) {
+	for _, log := range GroupRotated(entries) {
+		if log.Name == name {
+			return log, true
+		}
+	}
+
+	return RotatedLog{}, false
+}
+
+// MergeSegments creates a context spanning the parsed segments of a rotated log,
+// oldest first. Segments are ordered by their first timestamp, keeping the given
+// order for ties. A segment without any timestamp stays after the segment given
+// before it. Each line is copied with SegmentField set to the filename of its
+// segment, leaving the contexts of the segments unchanged.
+func MergeSegments(filenames []string, segments []*Context) *Context {
+	merged := NewContext(DefaultContextConfig())
+	for _, i := range segmentOrder(segments) {
+		seg := segments[i]
+		for _, line := range seg.lines {
+			fields := make(collections.Fields, len(line)+1)
+			for k, v := range line {
+				fields[k] = v
+			}
+			fields[SegmentField] = filenames[i]
+			merged.AddLine(fields)
+		}
+	}
+	merged.Analyze()
+
+	return merged
+}
+
+// segmentOrder returns the indices of segments ordered by their first timestamp,
+// as described by MergeSegments.
+func segmentOrder(segments []*Context) []int {
+	starts := make([]time.Time, len(segments))
+	var last time.Time
+	for i, seg := range segments {
+		if first, ok := seg.firstTimestamp(); ok {
+			last = first
+		}
+		starts[i] = last
+	}
+
+	order := make([]int, len(segments))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return starts[order[i]].Before(starts[order[j]])
+	})
+
+	return order
+}
+
+// firstTimestamp returns the first timestamp of the lines of a segment.
+func (c *Context) firstTimestamp() (time.Time, bool) {
+	for i := range c.lines {
+		if ts, ok := c.Timestamp(i); ok {
+			return ts, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,175 @@
+package logs
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+func TestGroupRotated(t *testing.T) {
+	var entries []Entry
+	for _, filename := range []string{
+		"logs/elastic-agent-abc/elastic-agent-20230104-1.ndjson",
+		"logs/elastic-agent-abc/elastic-agent-20230104.ndjson",
+		"logs/elastic-agent-abc/elastic-agent-20230103.ndjson.gz",
+		"logs/elastic-agent-abc/elastic-agent-20230104-10.ndjson",
+		"logs/elastic-agent-abc/elastic-agent-20230104-2.ndjson",
+		"logs/elastic-agent-def/elastic-agent-20230104.ndjson",
+		"logs/elastic-agent-abc/filebeat-20230104.ndjson",
+		"logs/elastic-agent-abc/filebeat-20230104-1.ndjson",
+		"logs/elastic-agent-abc/metricbeat-20230104.ndjson",
+		"logs/elastic-agent-abc/elastic-agent-json.log",
+	} {
+		entries = append(entries, Entry{Filename: filename, Type: GetType(filename), Component: GetComponent(filename)})
+	}
+
+	want := []RotatedLog{
+		{
+			Name:      "logs/elastic-agent-abc/elastic-agent-*.ndjson",
+			Type:      TypeNDJSON,
+			Component: ComponentAgent,
+			Segments: []string{
+				"logs/elastic-agent-abc/elastic-agent-20230103.ndjson.gz",
+				"logs/elastic-agent-abc/elastic-agent-20230104.ndjson",
+				"logs/elastic-agent-abc/elastic-agent-20230104-1.ndjson",
+				"logs/elastic-agent-abc/elastic-agent-20230104-2.ndjson",
+				"logs/elastic-agent-abc/elastic-agent-20230104-10.ndjson",
+			},
+		},
+		{
+			Name:      "logs/elastic-agent-abc/filebeat-*.ndjson",
+			Type:      TypeNDJSON,
+			Component: ComponentFilebeat,
+			Segments: []string{
+				"logs/elastic-agent-abc/filebeat-20230104.ndjson",
+				"logs/elastic-agent-abc/filebeat-20230104-1.ndjson",
+			},
+		},
+	}
+
+	require.Equal(t, want, GroupRotated(entries))
+
+	rotated, ok := FindRotated(entries, "logs/elastic-agent-abc/filebeat-*.ndjson")
+	require.True(t, ok)
+	require.Equal(t, want[1], rotated)
+
+	_, ok = FindRotated(entries, "logs/elastic-agent-abc/metricbeat-*.ndjson")
+	require.False(t, ok)
+}
+
+func TestMergeSegments(t *testing.T) {
+	first := NewContext(DefaultContextConfig())
+	first.AddLine(collections.Fields{"message": "one"})
+	first.AddLine(collections.Fields{"message": "two"})
+	first.Analyze()
+
+	second := NewContext(DefaultContextConfig())
+	second.AddLine(collections.Fields{"message": "three"})
+	second.Analyze()
+
+	merged := MergeSegments([]string{"a.ndjson", "a-1.ndjson"}, []*Context{first, second})
+
+	require.Equal(t, []collections.Fields{
+		{"message": "one", SegmentField: "a.ndjson"},
+		{"message": "two", SegmentField: "a.ndjson"},
+		{"message": "three", SegmentField: "a-1.ndjson"},
+	}, merged.ViewAll())
+	require.Contains(t, merged.Fields(), SegmentField)
+	require.Equal(t, []int{2}, merged.Filter(&TextFilter{Operator: FilterOpEquals, Field: SegmentField, Value: "a-1.ndjson"}))
+
+	// Segments are left unchanged.
+	require.Equal(t, []collections.Fields{{"message": "three"}}, second.ViewAll())
+}
+
+func TestMergeSegments_Order(t *testing.T) {
+	dir := filepath.Join("testdata", "segments")
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var entries []Entry
+	for _, file := range files {
+		entries = append(entries, Entry{Filename: path.Join(dir, file.Name())})
+	}
+	rotated := GroupRotated(entries)
+	require.Len(t, rotated, 1)
+
+	// The active segment has no sequence number, so it is named before the
+	// older segments of the same day.
+	require.Equal(t, []string{
+		"testdata/segments/elastic-agent-20230103.ndjson",
+		"testdata/segments/elastic-agent-20230104.ndjson",
+		"testdata/segments/elastic-agent-20230104-1.ndjson",
+		"testdata/segments/elastic-agent-20230104-2.ndjson",
+	}, rotated[0].Segments)
+
+	segments := make([]*Context, 0, len(rotated[0].Segments))
+	for _, filename := range rotated[0].Segments {
+		segments = append(segments, readNDJSON(t, filename))
+	}
+	merged := MergeSegments(rotated[0].Segments, segments)
+
+	var got []string
+	for _, line := range merged.ViewAll() {
+		got = append(got, line["message"].(string))
+	}
+	require.Equal(t, []string{
+		"segment one line one",
+		"segment one line two",
+		"segment two line one",
+		"segment two line two",
+		"segment three line one",
+		"segment four line one",
+		"segment four line two",
+	}, got)
+}
+
+func TestMergeSegments_NoTimestamps(t *testing.T) {
+	newSegment := func(lines ...collections.Fields) *Context {
+		c := NewContext(DefaultContextConfig())
+		for _, line := range lines {
+			c.AddLine(line)
+		}
+		c.Analyze()
+
+		return c
+	}
+
+	merged := MergeSegments([]string{"a.ndjson", "a-1.ndjson", "a-2.ndjson"}, []*Context{
+		newSegment(collections.Fields{"@timestamp": "2023-01-04T12:00:00Z", "message": "newest"}),
+		newSegment(collections.Fields{"message": "no timestamp"}),
+		newSegment(collections.Fields{"@timestamp": "2023-01-04T10:00:00Z", "message": "oldest"}),
+	})
+
+	var got []string
+	for _, line := range merged.ViewAll() {
+		got = append(got, line["message"].(string))
+	}
+	// The segment without timestamps stays after the segment named before it.
+	require.Equal(t, []string{"oldest", "newest", "no timestamp"}, got)
+}
+
+// readNDJSON reads an NDJSON file into an analyzed context.
+func readNDJSON(t *testing.T, filename string) *Context {
+	t.Helper()
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	c := NewContext(DefaultContextConfig())
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var line collections.Fields
+		require.NoError(t, dec.Decode(&line))
+		c.AddLine(line)
+	}
+	c.Analyze()
+
+	return c
+}
@@ -0,0 +1,253 @@
+package logs
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+// FieldType is the type of a log field, following the ECS field data types.
+type FieldType uint8
+
+const (
+	FieldTypeUnknown FieldType = iota
+	FieldTypeKeyword
+	FieldTypeText
+	FieldTypeDate
+	FieldTypeLong
+	FieldTypeFloat
+	FieldTypeBoolean
+	FieldTypeIP
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldTypeKeyword:
+		return "keyword"
+	case FieldTypeText:
+		return "text"
+	case FieldTypeDate:
+		return "date"
+	case FieldTypeLong:
+		return "long"
+	case FieldTypeFloat:
+		return "float"
+	case FieldTypeBoolean:
+		return "boolean"
+	case FieldTypeIP:
+		return "ip"
+	}
+
+	return "unknown"
+}
+
+// IsOrdered returns true if values of the type can be compared by order.
+func (t FieldType) IsOrdered() bool {
+	return t == FieldTypeDate || t == FieldTypeLong || t == FieldTypeFloat
+}
+
+// ecsSchema holds the types of common ECS fields found in Elastic logs.
+var ecsSchema = map[string]FieldType{
+	TimestampField: FieldTypeDate,
+	"message":      FieldTypeText,
+	"labels":       FieldTypeKeyword,
+	"tags":         FieldTypeKeyword,
+
+	"agent.id":      FieldTypeKeyword,
+	"agent.name":    FieldTypeKeyword,
+	"agent.type":    FieldTypeKeyword,
+	"agent.version": FieldTypeKeyword,
+
+	"client.ip":        FieldTypeIP,
+	"client.port":      FieldTypeLong,
+	"destination.ip":   FieldTypeIP,
+	"destination.port": FieldTypeLong,
+	"host.ip":          FieldTypeIP,
+	"host.name":        FieldTypeKeyword,
+	"host.hostname":    FieldTypeKeyword,
+	"server.ip":        FieldTypeIP,
+	"server.port":      FieldTypeLong,
+	"source.ip":        FieldTypeIP,
+	"source.port":      FieldTypeLong,
+
+	"ecs.version": FieldTypeKeyword,
+
+	"error.code":        FieldTypeKeyword,
+	"error.message":     FieldTypeText,
+	"error.stack_trace": FieldTypeText,
+	"error.type":        FieldTypeKeyword,
+
+	"event.action":   FieldTypeKeyword,
+	"event.category": FieldTypeKeyword,
+	"event.code":     FieldTypeKeyword,
+	"event.created":  FieldTypeDate,
+	"event.dataset":  FieldTypeKeyword,
+	"event.duration": FieldTypeLong,
+	"event.end":      FieldTypeDate,
+	"event.ingested": FieldTypeDate,
+	"event.kind":     FieldTypeKeyword,
+	"event.module":   FieldTypeKeyword,
+	"event.outcome":  FieldTypeKeyword,
+	"event.sequence": FieldTypeLong,
+	"event.start":    FieldTypeDate,
+	"event.type":     FieldTypeKeyword,
+
+	"http.request.method":       FieldTypeKeyword,
+	"http.response.status_code": FieldTypeLong,
+
+	"log.file.path":        FieldTypeKeyword,
+	"log.level":            FieldTypeKeyword,
+	"log.logger":           FieldTypeKeyword,
+	"log.offset":           FieldTypeLong,
+	"log.origin.file.line": FieldTypeLong,
+	"log.origin.file.name": FieldTypeKeyword,
+	"log.origin.function":  FieldTypeKeyword,
+
+	"process.name":      FieldTypeKeyword,
+	"process.pid":       FieldTypeLong,
+	"process.thread.id": FieldTypeLong,
+
+	"service.id":      FieldTypeKeyword,
+	"service.name":    FieldTypeKeyword,
+	"service.type":    FieldTypeKeyword,
+	"service.version": FieldTypeKeyword,
+
+	"url.domain":   FieldTypeKeyword,
+	"url.full":     FieldTypeKeyword,
+	"url.original": FieldTypeKeyword,
+	"url.path":     FieldTypeKeyword,
+	"url.port":     FieldTypeLong,
+}
+
+// dateLayouts are the layouts accepted for date fields. Layouts without a
+// timezone are taken to be UTC.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999 Z07:00",
+	"2006-01-02 15:04:05.999999999 Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999999999",
+}
+
+// SchemaType returns the ECS type of a field, if it is a known ECS field.
+func SchemaType(field string) (FieldType, bool) {
+	t, ok := ecsSchema[field]
+
+	return t, ok
+}
+
+// InferType infers the type of a field from one of its values.
+func InferType(value any) FieldType {
+	switch v := value.(type) {
+	case bool:
+		return FieldTypeBoolean
+	case float64:
+		if v == float64(int64(v)) {
+			return FieldTypeLong
+		}
+		return FieldTypeFloat
+	case string:
+		if looksLikeDate(v) {
+			if _, ok := ParseDate(v); ok {
+				return FieldTypeDate
+			}
+		}
+		if net.ParseIP(v) != nil {
+			return FieldTypeIP
+		}
+		return FieldTypeKeyword
+	}
+
+	return FieldTypeUnknown
+}
+
+// mergeTypes returns the type of a field whose values have both types. Mixed
+// numbers are floats, while any other mix is treated as keywords.
+func mergeTypes(a, b FieldType) FieldType {
+	switch {
+	case a == b || b == FieldTypeUnknown:
+		return a
+	case a == FieldTypeUnknown:
+		return b
+	case (a == FieldTypeLong || a == FieldTypeFloat) && (b == FieldTypeLong || b == FieldTypeFloat):
+		return FieldTypeFloat
+	}
+
+	return FieldTypeKeyword
+}
+
+// looksLikeDate is a cheap check for a "YYYY-MM-DD" prefix, avoiding trying
+// every date layout on each string value.
+func looksLikeDate(value string) bool {
+	return len(value) >= 19 && value[4] == '-' && value[7] == '-'
+}
+
+// ParseDate parses the value of a date field.
+func ParseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Coerce converts the values of known ECS fields to the JSON type of their
+// field, in place. Numbers and booleans encoded as strings are decoded, and
+// scalar values of keyword fields are encoded as strings.
+func Coerce(line collections.Fields) {
+	coerce("", line)
+}
+
+func coerce(prefix string, m map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch value := v.(type) {
+		case collections.Fields:
+			coerce(key, value)
+		case map[string]any:
+			coerce(key, value)
+		default:
+			if t, ok := ecsSchema[key]; ok {
+				m[k] = coerceValue(t, value)
+			}
+		}
+	}
+}
+
+func coerceValue(t FieldType, value any) any {
+	switch t {
+	case FieldTypeLong, FieldTypeFloat:
+		if s, ok := value.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+	case FieldTypeBoolean:
+		if s, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	case FieldTypeKeyword:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,74 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+func TestInferType(t *testing.T) {
+	tests := map[string]struct {
+		In   any
+		Want FieldType
+	}{
+		"bool":        {In: true, Want: FieldTypeBoolean},
+		"long":        {In: float64(42), Want: FieldTypeLong},
+		"float":       {In: 4.2, Want: FieldTypeFloat},
+		"date":        {In: "2023-01-05T10:00:00.123Z", Want: FieldTypeDate},
+		"date_offset": {In: "2023-01-05T10:00:00.123-0500", Want: FieldTypeDate},
+		"ipv4":        {In: "10.0.0.1", Want: FieldTypeIP},
+		"ipv6":        {In: "fe80::1", Want: FieldTypeIP},
+		"keyword":     {In: "info", Want: FieldTypeKeyword},
+		"nil":         {In: nil, Want: FieldTypeUnknown},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.Want, InferType(tc.In))
+		})
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	line := collections.Fields{
+		"event": map[string]any{
+			"duration": "1500",
+			"dataset":  "elastic_agent",
+		},
+		"process":   map[string]any{"pid": "bad"},
+		"log.level": float64(3),
+		"custom":    "42",
+	}
+	Coerce(line)
+
+	require.Equal(t, collections.Fields{
+		"event": map[string]any{
+			"duration": float64(1500),
+			"dataset":  "elastic_agent",
+		},
+		// Values which can't be converted are left as is.
+		"process": map[string]any{"pid": "bad"},
+		// Literal dotted keys are matched by their full path.
+		"log.level": "3",
+		// Fields outside the schema are not coerced.
+		"custom": "42",
+	}, line)
+}
+
+func TestContext_FieldType(t *testing.T) {
+	c := NewContext(DefaultContextConfig())
+	c.AddLine(collections.Fields{"@timestamp": "not a date", "count": float64(1), "ratio": float64(1), "mixed": "a", "addr": "10.0.0.1"})
+	c.AddLine(collections.Fields{"count": float64(2), "ratio": 0.5, "mixed": true, "addr": "10.0.0.2"})
+	c.Analyze()
+
+	// Schema types take precedence over the values.
+	require.Equal(t, FieldTypeDate, c.FieldType("@timestamp"))
+	require.Equal(t, FieldTypeLong, c.FieldType("count"))
+	require.Equal(t, FieldTypeFloat, c.FieldType("ratio"))
+	require.Equal(t, FieldTypeKeyword, c.FieldType("mixed"))
+	require.Equal(t, FieldTypeIP, c.FieldType("addr"))
+	require.Equal(t, FieldTypeUnknown, c.FieldType("missing"))
+}
@@ -0,0 +1,123 @@
+package logs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SniffSize is the number of bytes read from the start of a file to detect its
+// compression and parser.
+const SniffSize = 4096
+
+// SniffFunc reports whether the start of a file looks like content handled by
+// a parser.
+type SniffFunc = func(head []byte) bool
+
+// Detection describes how a log file is read.
+type Detection struct {
+	// Parser is the name of the parser for the file.
+	Parser string
+	// Compression is the compression of the file, or empty if it is not
+	// compressed.
+	Compression string
+}
+
+func RegisterSniffer(name string, fn SniffFunc) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; !exists {
+		return ErrParserUnsupported
+	}
+	if _, exists := registrySniffers[name]; exists {
+		return ErrParserExists
+	}
+	registrySniffers[name] = fn
+
+	return nil
+}
+
+// sniff reads the start of r, returning a reader of the uncompressed content of
+// r, its first SniffSize bytes and its compression.
+func sniff(r io.Reader) (io.Reader, []byte, string, error) {
+	r, compression, err := Decompress(r)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	br := bufio.NewReaderSize(r, SniffSize)
+	head, err := br.Peek(SniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, "", err
+	}
+
+	return br, head, compression, nil
+}
+
+// DetectParser returns the name of the parser for a file, given its name and
+// its first bytes. A parser registered for the file's extension is used unless
+// it has a sniffer which rejects the content. Otherwise, such as for files with
+// an unknown extension or one mapped to the generic parser, the first parser
+// whose sniffer matches is used, falling back to the parser for the extension
+// or the generic parser.
+func DetectParser(filename string, head []byte) (string, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	byExt, extOK := registryFileTypes[fileExt(filename)]
+	if extOK && byExt != registryFileTypes[""] {
+		if fn, ok := registrySniffers[byExt]; !ok || fn(head) {
+			return byExt, nil
+		}
+	}
+
+	names := make([]string, 0, len(registrySniffers))
+	for name := range registrySniffers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if registrySniffers[name](head) {
+			return name, nil
+		}
+	}
+
+	if extOK {
+		return byExt, nil
+	}
+	if generic, ok := registryFileTypes[""]; ok {
+		return generic, nil
+	}
+
+	return "", ErrFileTypeUnsupported
+}
+
+// Detect reads the start of r to detect how to read the file. If parser is not
+// empty, it is used instead of the detected parser. The returned reader reads
+// the uncompressed content of r.
+func Detect(r io.Reader, filename, parser string) (io.Reader, Detection, error) {
+	r, head, compression, err := sniff(r)
+	if err != nil {
+		return nil, Detection{}, err
+	}
+
+	if parser == "" {
+		if parser, err = DetectParser(filename, head); err != nil {
+			return nil, Detection{}, err
+		}
+	} else if !HasParser(parser) {
+		return nil, Detection{}, fmt.Errorf("%w: %q", ErrParserUnsupported, parser)
+	}
+
+	return r, Detection{Parser: parser, Compression: compression}, nil
+}
+
+// fileExt returns the extension of a file used to look up its parser.
+func fileExt(filename string) string {
+	return strings.ToLower(filepath.Ext(TrimCompressionExt(filename)))
+}
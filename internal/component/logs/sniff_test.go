@@ -0,0 +1,138 @@
+package logs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/component/logs/ndjson"
+	"github.com/taylor-swanson/sawmill/internal/component/logs/text"
+)
+
+const (
+	testJSONLines = "{\"message\":\"hello\"}\n{\"message\":\"world\"}\n"
+	testTextLines = "hello\nworld\n"
+)
+
+func gzipped(t *testing.T, data string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.String()
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]struct {
+		filename        string
+		content         string
+		parser          string
+		wantParser      string
+		wantCompression string
+		wantContent     string
+		wantErr         error
+	}{
+		"ndjson_extension": {
+			filename:   "elastic-agent.ndjson",
+			content:    testJSONLines,
+			wantParser: ndjson.Name,
+		},
+		"ndjson_extension_text_content": {
+			filename:   "elastic-agent.ndjson",
+			content:    testTextLines,
+			wantParser: ndjson.Name,
+		},
+		"text_extension": {
+			filename:   "log.txt",
+			content:    testTextLines,
+			wantParser: text.Name,
+		},
+		"text_extension_json_content": {
+			filename:   "log.txt",
+			content:    testJSONLines,
+			wantParser: ndjson.Name,
+		},
+		"unknown_extension_json": {
+			filename:   "elastic-agent-json.log",
+			content:    "\n  " + testJSONLines,
+			wantParser: ndjson.Name,
+		},
+		"unknown_extension_text": {
+			filename:   "elastic-agent.log",
+			content:    testTextLines,
+			wantParser: text.Name,
+		},
+		"truncated_json_line": {
+			filename:   "elastic-agent.log",
+			content:    "{\"message\":\"" + strings.Repeat("x", logs.SniffSize) + "\"}\n",
+			wantParser: ndjson.Name,
+		},
+		"invalid_json_line": {
+			filename:   "elastic-agent.log",
+			content:    "{not json}\n",
+			wantParser: text.Name,
+		},
+		"empty": {
+			filename:   "elastic-agent.log",
+			wantParser: text.Name,
+		},
+		"gzip": {
+			filename:        "elastic-agent.ndjson.gz",
+			content:         gzipped(t, testJSONLines),
+			wantParser:      ndjson.Name,
+			wantCompression: logs.CompressionGzip,
+			wantContent:     testJSONLines,
+		},
+		"gzip_sniffed": {
+			filename:        "elastic-agent.gz",
+			content:         gzipped(t, testJSONLines),
+			wantParser:      ndjson.Name,
+			wantCompression: logs.CompressionGzip,
+			wantContent:     testJSONLines,
+		},
+		"override": {
+			filename:   "elastic-agent.ndjson",
+			content:    testJSONLines,
+			parser:     text.Name,
+			wantParser: text.Name,
+		},
+		"override_unsupported": {
+			filename: "elastic-agent.ndjson",
+			content:  testJSONLines,
+			parser:   "xml",
+			wantErr:  logs.ErrParserUnsupported,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r, detection, err := logs.Detect(strings.NewReader(tc.content), tc.filename, tc.parser)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantParser, detection.Parser)
+			require.Equal(t, tc.wantCompression, detection.Compression)
+
+			// The reader still returns the whole, uncompressed content.
+			wantContent := tc.wantContent
+			if wantContent == "" {
+				wantContent = tc.content
+			}
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, wantContent, string(content))
+		})
+	}
+}
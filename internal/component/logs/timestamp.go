@@ -0,0 +1,74 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeExpr parses an absolute date, in any of the layouts accepted for
+// date fields, or a time relative to now, such as "now", "now-15m" or
+// "now+1d". Relative offsets are Go durations, additionally accepting days.
+func ParseTimeExpr(expr string, now time.Time) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	rest, ok := strings.CutPrefix(expr, "now")
+	if !ok {
+		if t, ok := ParseDate(expr); ok {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("invalid time: %q", expr)
+	}
+	if rest == "" {
+		return now, nil
+	}
+
+	var sign time.Duration
+	switch rest[0] {
+	case '+':
+		sign = 1
+	case '-':
+		sign = -1
+	default:
+		return time.Time{}, fmt.Errorf("invalid relative time: %q", expr)
+	}
+	offset, err := parseOffset(rest[1:])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid relative time: %q: %w", expr, err)
+	}
+
+	return now.Add(sign * offset), nil
+}
+
+// parseOffset parses a duration, accepting a number of days such as "2d".
+func parseOffset(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// leadingTimestamp finds a timestamp at the start of a plain text line, such
+// as "2023-01-04T22:53:22.000Z" or "2023-01-04 22:53:22,000", returning it
+// normalized to RFC 3339.
+func leadingTimestamp(line string) (string, bool) {
+	if !looksLikeDate(line) {
+		return "", false
+	}
+
+	// Dates and times separated by a space span two tokens, optionally
+	// followed by a timezone offset.
+	tokens := strings.SplitN(line, " ", 4)
+	for n := min(len(tokens), 3); n > 0; n-- {
+		if t, ok := ParseDate(strings.Join(tokens[:n], " ")); ok {
+			return t.Format(time.RFC3339Nano), true
+		}
+	}
+
+	return "", false
+}
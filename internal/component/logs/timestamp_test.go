@@ -0,0 +1,67 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+)
+
+func TestParseTimeExpr(t *testing.T) {
+	now := time.Date(2023, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		In      string
+		Want    time.Time
+		WantErr bool
+	}{
+		"now":          {In: "now", Want: now},
+		"minus":        {In: "now-15m", Want: now.Add(-15 * time.Minute)},
+		"plus":         {In: "now+1h30m", Want: now.Add(90 * time.Minute)},
+		"days":         {In: "now-2d", Want: now.Add(-48 * time.Hour)},
+		"absolute":     {In: "2023-01-04T10:00:00Z", Want: time.Date(2023, 1, 4, 10, 0, 0, 0, time.UTC)},
+		"no_sign":      {In: "now15m", WantErr: true},
+		"bad_duration": {In: "now-15x", WantErr: true},
+		"bad_date":     {In: "yesterday", WantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTimeExpr(tc.In, now)
+			if tc.WantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.Want.Equal(got), "want %s, got %s", tc.Want, got)
+		})
+	}
+}
+
+func TestContext_Timestamp(t *testing.T) {
+	want := time.Date(2023, 1, 4, 22, 53, 22, 500_000_000, time.UTC)
+
+	c := NewContext(DefaultContextConfig())
+	c.AddLine(collections.Fields{TimestampField: "2023-01-04T22:53:22.500Z"})
+	c.AddLine(collections.Fields{TimestampField: "2023-01-04T17:53:22.500-05:00"})
+	c.AddLine(collections.Fields{TimestampField: "2023-01-04T23:53:22.500+0100"})
+	c.AddLine(collections.Fields{TimestampField: "2023-01-04 22:53:22.500"})
+	c.AddLineRaw("2023-01-04 22:53:22,500 INFO starting")
+	c.AddLineRaw("2023-01-04 23:53:22.500 +01:00 INFO starting")
+	c.AddLine(collections.Fields{TimestampField: "invalid"})
+	c.AddLineRaw("no timestamp")
+	c.Analyze()
+
+	for i := 0; i < 6; i++ {
+		got, ok := c.Timestamp(i)
+		require.True(t, ok, "line %d", i)
+		require.True(t, want.Equal(got), "line %d: want %s, got %s", i, want, got)
+	}
+	for i := 6; i < 8; i++ {
+		_, ok := c.Timestamp(i)
+		require.False(t, ok, "line %d", i)
+	}
+}
@@ -0,0 +1,173 @@
+// Package metrics extracts time series from the periodic monitoring snapshots
+// Beats write to their logs ("Non-zero metrics in the last 30s").
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// MetricsField is the field of a log line holding a monitoring snapshot.
+const MetricsField = "monitoring.metrics"
+
+// sourceFields are the fields identifying who wrote a snapshot, in order of
+// preference. Components running under the agent log through the agent, so
+// a single log may hold snapshots from several sources.
+var sourceFields = []string{"component.id", "service.name"}
+
+// Point is the value of a metric in one snapshot.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is the values of a metric from one source over time.
+type Series struct {
+	Source string
+	Metric string
+	Points []Point
+}
+
+// ChartSpec describes a chart of related metrics.
+type ChartSpec struct {
+	Title   string
+	Metrics []string
+}
+
+// DefaultCharts are the charts shown for every source. Counters hold the
+// change since the previous snapshot.
+var DefaultCharts = []ChartSpec{
+	{
+		Title: "Events per interval",
+		Metrics: []string{
+			"libbeat.pipeline.events.published",
+			"libbeat.output.events.acked",
+			"libbeat.output.events.total",
+		},
+	},
+	{
+		Title: "Dropped and failed events per interval",
+		Metrics: []string{
+			"libbeat.pipeline.events.dropped",
+			"libbeat.pipeline.events.failed",
+			"libbeat.output.events.dropped",
+			"libbeat.output.events.failed",
+		},
+	},
+	{
+		Title: "Queue fill",
+		Metrics: []string{
+			"libbeat.pipeline.queue.filled.events",
+			"libbeat.pipeline.queue.filled.pct",
+			"libbeat.pipeline.queue.filled.pct.events",
+		},
+	},
+	{
+		Title: "Open files",
+		Metrics: []string{
+			"filebeat.harvester.open_files",
+		},
+	},
+	{
+		Title: "Memory (RSS bytes)",
+		Metrics: []string{
+			"beat.memstats.rss",
+		},
+	},
+}
+
+// Extract builds a time series for every numeric metric found in the
+// monitoring snapshots of a log, ordered by source and metric. Snapshots only
+// include non-zero values, so a metric missing from a snapshot of its source
+// is recorded as zero.
+func Extract(logCtx *logs.Context) []*Series {
+	type snapshot struct {
+		time    time.Time
+		metrics collections.Fields
+	}
+	snapshots := map[string][]snapshot{}
+
+	for i, line := range logCtx.ViewAll() {
+		value, ok := line.Get(MetricsField)
+		if !ok {
+			continue
+		}
+		fields, ok := value.(collections.Fields)
+		if !ok {
+			continue
+		}
+		ts, ok := logCtx.Timestamp(i)
+		if !ok {
+			continue
+		}
+
+		source := lineSource(line)
+		snapshots[source] = append(snapshots[source], snapshot{time: ts, metrics: fields.Flatten()})
+	}
+
+	var series []*Series
+	for source, snaps := range snapshots {
+		names := collections.NewSet[string]()
+		for _, snap := range snaps {
+			for k, v := range snap.metrics {
+				if _, ok := v.(float64); ok {
+					names.Add(k)
+				}
+			}
+		}
+
+		for _, name := range names.Values() {
+			s := &Series{Source: source, Metric: name, Points: make([]Point, 0, len(snaps))}
+			for _, snap := range snaps {
+				v, _ := snap.metrics[name].(float64)
+				s.Points = append(s.Points, Point{Time: snap.time, Value: v})
+			}
+			series = append(series, s)
+		}
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Source != series[j].Source {
+			return series[i].Source < series[j].Source
+		}
+		return series[i].Metric < series[j].Metric
+	})
+
+	return series
+}
+
+// Sources returns the sources of the series, in order.
+func Sources(series []*Series) []string {
+	var sources []string
+	for _, s := range series {
+		if len(sources) == 0 || sources[len(sources)-1] != s.Source {
+			sources = append(sources, s.Source)
+		}
+	}
+
+	return sources
+}
+
+// Find returns the series of a metric from a source.
+func Find(series []*Series, source string, metric string) (*Series, bool) {
+	for _, s := range series {
+		if s.Source == source && s.Metric == metric {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func lineSource(line collections.Fields) string {
+	for _, field := range sourceFields {
+		if v, ok := line.GetString(field); ok && v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
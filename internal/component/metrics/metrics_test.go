@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+func newTestContext(t *testing.T, lines ...string) *logs.Context {
+	t.Helper()
+
+	c := logs.NewContext(logs.DefaultContextConfig())
+	for _, line := range lines {
+		fields := collections.Fields{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		c.AddLine(fields)
+	}
+	c.Analyze()
+
+	return c
+}
+
+func TestExtract(t *testing.T) {
+	c := newTestContext(t,
+		`{"@timestamp":"2023-01-04T22:53:00Z","message":"Non-zero metrics in the last 30s","component":{"id":"filestream-default"},"monitoring":{"metrics":{"libbeat":{"output":{"events":{"acked":100}}},"beat":{"memstats":{"rss":2048}}}}}`,
+		`{"@timestamp":"2023-01-04T22:53:10Z","message":"unrelated"}`,
+		`{"@timestamp":"2023-01-04T22:53:30Z","message":"Non-zero metrics in the last 30s","component":{"id":"filestream-default"},"monitoring":{"metrics":{"beat":{"memstats":{"rss":4096},"info":{"uptime":{"ms":60000}}}}}}`,
+		`{"@timestamp":"2023-01-04T22:53:30Z","message":"Non-zero metrics in the last 30s","service.name":"metricbeat","monitoring":{"metrics":{"libbeat":{"output":{"events":{"acked":5}}}}}}`,
+	)
+
+	t0, _ := time.Parse(time.RFC3339, "2023-01-04T22:53:00Z")
+	t1 := t0.Add(30 * time.Second)
+
+	got := Extract(c)
+
+	require.Len(t, got, 4)
+	require.Equal(t, []string{"filestream-default", "metricbeat"}, Sources(got))
+
+	acked, ok := Find(got, "filestream-default", "libbeat.output.events.acked")
+	require.True(t, ok)
+	require.Equal(t, []Point{{Time: t0, Value: 100}, {Time: t1, Value: 0}}, acked.Points)
+
+	rss, ok := Find(got, "filestream-default", "beat.memstats.rss")
+	require.True(t, ok)
+	require.Equal(t, []Point{{Time: t0, Value: 2048}, {Time: t1, Value: 4096}}, rss.Points)
+
+	uptime, ok := Find(got, "filestream-default", "beat.info.uptime.ms")
+	require.True(t, ok)
+	require.Equal(t, []Point{{Time: t0, Value: 0}, {Time: t1, Value: 60000}}, uptime.Points)
+
+	mb, ok := Find(got, "metricbeat", "libbeat.output.events.acked")
+	require.True(t, ok)
+	require.Equal(t, []Point{{Time: t1, Value: 5}}, mb.Points)
+
+	_, ok = Find(got, "metricbeat", "beat.memstats.rss")
+	require.False(t, ok)
+}
@@ -0,0 +1,217 @@
+// Package policy parses agent policies, describing the outputs the agent sends
+// data to and the inputs it runs.
+package policy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/redact"
+)
+
+const (
+	// DefaultOutput is the output used by inputs that do not name one.
+	DefaultOutput = "default"
+	// DefaultNamespace is the namespace used by data streams that do not name
+	// one.
+	DefaultNamespace = "default"
+)
+
+// Policy is an agent policy.
+type Policy struct {
+	ID       string
+	Revision int
+	Outputs  []Output
+	Inputs   []Input
+}
+
+// Output is an output the agent sends data to. Secrets in its settings are
+// redacted.
+type Output struct {
+	Name  string
+	Type  string
+	Hosts []string
+	// Settings holds every other output setting, keyed by its dotted path.
+	Settings collections.Fields
+}
+
+// Package is the integration package an input belongs to.
+type Package struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// Meta is the metadata Fleet attaches to an input.
+type Meta struct {
+	Package Package `yaml:"package"`
+}
+
+// DataStream identifies where the data of an input or stream is written.
+type DataStream struct {
+	Type      string `yaml:"type"`
+	Dataset   string `yaml:"dataset"`
+	Namespace string `yaml:"namespace"`
+}
+
+// Stream is a data stream collected by an input.
+type Stream struct {
+	ID         string     `yaml:"id"`
+	Enabled    *bool      `yaml:"enabled"`
+	DataStream DataStream `yaml:"data_stream"`
+}
+
+// IsEnabled returns true unless the stream is explicitly disabled.
+func (s Stream) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// Input is an input run by the agent.
+type Input struct {
+	ID         string     `yaml:"id"`
+	Name       string     `yaml:"name"`
+	Type       string     `yaml:"type"`
+	Revision   int        `yaml:"revision"`
+	UseOutput  string     `yaml:"use_output"`
+	Meta       Meta       `yaml:"meta"`
+	DataStream DataStream `yaml:"data_stream"`
+	Streams    []Stream   `yaml:"streams"`
+}
+
+// Output returns the name of the output the input sends data to.
+func (i Input) Output() string {
+	if i.UseOutput == "" {
+		return DefaultOutput
+	}
+
+	return i.UseOutput
+}
+
+// Namespace returns the data stream namespace of the input.
+func (i Input) Namespace() string {
+	if i.DataStream.Namespace == "" {
+		return DefaultNamespace
+	}
+
+	return i.DataStream.Namespace
+}
+
+// ComponentID returns the ID of the component the agent runs the input in,
+// as found in the component.id field of its logs.
+func (i Input) ComponentID() string {
+	return i.Type + "-" + i.Output()
+}
+
+// PackageGroup is the set of inputs belonging to one version of a package.
+type PackageGroup struct {
+	Package Package
+	Inputs  []Input
+}
+
+// Packages groups the inputs of the policy by package, ordered by name and
+// version. Inputs without a package are grouped under an empty package.
+func (p Policy) Packages() []PackageGroup {
+	var groups []PackageGroup
+	index := map[Package]int{}
+
+	for _, input := range p.Inputs {
+		i, ok := index[input.Meta.Package]
+		if !ok {
+			i = len(groups)
+			index[input.Meta.Package] = i
+			groups = append(groups, PackageGroup{Package: input.Meta.Package})
+		}
+		groups[i].Inputs = append(groups[i].Inputs, input)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Package.Name != groups[j].Package.Name {
+			return groups[i].Package.Name < groups[j].Package.Name
+		}
+		return groups[i].Package.Version < groups[j].Package.Version
+	})
+
+	return groups
+}
+
+// Namespaces returns the sorted data stream namespaces used by the inputs and
+// streams of the policy.
+func (p Policy) Namespaces() []string {
+	namespaces := collections.NewSet[string]()
+
+	for _, input := range p.Inputs {
+		namespaces.Add(input.Namespace())
+		for _, stream := range input.Streams {
+			if stream.DataStream.Namespace != "" {
+				namespaces.Add(stream.DataStream.Namespace)
+			}
+		}
+	}
+
+	values := namespaces.Values()
+	sort.Strings(values)
+
+	return values
+}
+
+// Parse parses an agent policy.
+func Parse(r io.Reader) (Policy, error) {
+	var raw struct {
+		ID       string                    `yaml:"id"`
+		Revision int                       `yaml:"revision"`
+		Outputs  map[string]map[string]any `yaml:"outputs"`
+		Inputs   []Input                   `yaml:"inputs"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return Policy{}, fmt.Errorf("unable to decode policy: %w", err)
+	}
+
+	p := Policy{
+		ID:       raw.ID,
+		Revision: raw.Revision,
+		Inputs:   raw.Inputs,
+	}
+
+	for name, settings := range raw.Outputs {
+		p.Outputs = append(p.Outputs, parseOutput(name, settings))
+	}
+	sort.Slice(p.Outputs, func(i, j int) bool {
+		return p.Outputs[i].Name < p.Outputs[j].Name
+	})
+
+	return p, nil
+}
+
+func parseOutput(name string, settings map[string]any) Output {
+	out := Output{
+		Name:     name,
+		Settings: collections.Fields{},
+	}
+
+	for k, v := range collections.Fields(settings).Flatten() {
+		switch k {
+		case "type":
+			out.Type = fmt.Sprint(v)
+			continue
+		case "hosts":
+			if hosts, ok := v.([]any); ok {
+				for _, host := range hosts {
+					out.Hosts = append(out.Hosts, redact.Line(fmt.Sprint(host)))
+				}
+				continue
+			}
+		}
+
+		if redact.IsSensitiveKey(k) {
+			out.Settings[k] = redact.Placeholder
+		} else {
+			out.Settings[k] = redact.Value(v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/redact"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "policy.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := Parse(f)
+	require.NoError(t, err)
+
+	require.Equal(t, "2016d7cc-135e-5583-9758-3ba01f5a06e5", got.ID)
+	require.Equal(t, 4, got.Revision)
+
+	require.Len(t, got.Outputs, 2)
+	es := got.Outputs[0]
+	require.Equal(t, "default", es.Name)
+	require.Equal(t, "elasticsearch", es.Type)
+	require.Equal(t, []string{"https://" + redact.Placeholder + "@es01:9200", "https://es02:9200"}, es.Hosts)
+	require.Equal(t, redact.Placeholder, es.Settings["api_key"])
+	require.Equal(t, redact.Placeholder, es.Settings["ssl.key_passphrase"])
+	require.Equal(t, redact.Placeholder, es.Settings["ssl.key"])
+	require.Equal(t, redact.Placeholder, es.Settings["ssl.proxy.key"])
+	require.Equal(t, "/etc/certs/client.crt", es.Settings["ssl.certificate"])
+	require.Equal(t, []any{"/etc/certs/ca.crt"}, es.Settings["ssl.certificate_authorities"])
+	require.Equal(t, "logstash", got.Outputs[1].Type)
+
+	require.Len(t, got.Inputs, 3)
+	require.Equal(t, "logfile-default", got.Inputs[0].ComponentID())
+	require.Equal(t, "system/metrics-default", got.Inputs[1].ComponentID())
+	require.Equal(t, "httpjson-default", got.Inputs[2].ComponentID())
+	require.True(t, got.Inputs[0].Streams[0].IsEnabled())
+	require.False(t, got.Inputs[0].Streams[1].IsEnabled())
+
+	require.Equal(t, []string{"default", "security"}, got.Namespaces())
+}
+
+func TestPolicy_Packages(t *testing.T) {
+	p := Policy{
+		Inputs: []Input{
+			{ID: "a", Meta: Meta{Package: Package{Name: "system", Version: "1.20.4"}}},
+			{ID: "b", Meta: Meta{Package: Package{Name: "okta", Version: "1.15.0"}}},
+			{ID: "c", Meta: Meta{Package: Package{Name: "system", Version: "1.20.4"}}},
+			{ID: "d"},
+		},
+	}
+
+	got := p.Packages()
+
+	require.Len(t, got, 3)
+	require.Equal(t, "", got[0].Package.Name)
+	require.Equal(t, "okta", got[1].Package.Name)
+	require.Equal(t, "system", got[2].Package.Name)
+	require.Len(t, got[2].Inputs, 2)
+	require.Equal(t, "a", got[2].Inputs[0].ID)
+	require.Equal(t, "c", got[2].Inputs[1].ID)
+}
@@ -0,0 +1,301 @@
+// Package profile summarizes pprof profiles found in diagnostics bundles.
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// minFlameFraction is the smallest fraction of the total a flame graph node
+// must have to be kept. Smaller nodes are too narrow to be useful.
+const minFlameFraction = 0.005
+
+// Profile is a parsed pprof profile.
+type Profile struct {
+	p *profile.Profile
+}
+
+// Parse parses a pprof profile, which may be gzip compressed.
+func Parse(r io.Reader) (*Profile, error) {
+	p, err := profile.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse profile: %w", err)
+	}
+
+	return &Profile{p: p}, nil
+}
+
+// SampleTypes returns the names of the sample types in the profile, such as
+// "inuse_space" or "goroutine".
+func (p *Profile) SampleTypes() []string {
+	types := make([]string, 0, len(p.p.SampleType))
+	for _, v := range p.p.SampleType {
+		types = append(types, v.Type)
+	}
+
+	return types
+}
+
+// IsGoroutine returns true if this is a goroutine profile.
+func (p *Profile) IsGoroutine() bool {
+	return len(p.p.SampleType) == 1 && p.p.SampleType[0].Type == "goroutine"
+}
+
+// SampleIndex returns the index of the named sample type. An empty name selects
+// the profile's default sample type.
+func (p *Profile) SampleIndex(name string) (int, error) {
+	if name == "" {
+		name = p.p.DefaultSampleType
+	}
+	if name == "" {
+		return len(p.p.SampleType) - 1, nil
+	}
+
+	for i, v := range p.p.SampleType {
+		if v.Type == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown sample type: %q", name)
+}
+
+// Unit returns the unit of a sample type.
+func (p *Profile) Unit(index int) string {
+	return p.p.SampleType[index].Unit
+}
+
+// Total returns the sum of all samples of a sample type.
+func (p *Profile) Total(index int) int64 {
+	var total int64
+	for _, s := range p.p.Sample {
+		total += s.Value[index]
+	}
+
+	return total
+}
+
+// Function is a function's share of a profile.
+type Function struct {
+	Name string
+	// Flat is the value of samples in the function itself.
+	Flat int64
+	// Cum is the value of samples in the function and its callees.
+	Cum int64
+}
+
+// Top returns up to n functions with the highest flat values.
+func (p *Profile) Top(index int, n int) []Function {
+	funcs := map[string]*Function{}
+	get := func(name string) *Function {
+		f, ok := funcs[name]
+		if !ok {
+			f = &Function{Name: name}
+			funcs[name] = f
+		}
+		return f
+	}
+
+	for _, s := range p.p.Sample {
+		v := s.Value[index]
+		if v == 0 {
+			continue
+		}
+		stack := stackNames(s)
+		if len(stack) == 0 {
+			continue
+		}
+		get(stack[0]).Flat += v
+
+		// Count each function once per sample, even if it recurses.
+		seen := map[string]bool{}
+		for _, name := range stack {
+			if !seen[name] {
+				seen[name] = true
+				get(name).Cum += v
+			}
+		}
+	}
+
+	top := make([]Function, 0, len(funcs))
+	for _, f := range funcs {
+		top = append(top, *f)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Flat != top[j].Flat {
+			return top[i].Flat > top[j].Flat
+		}
+		if top[i].Cum != top[j].Cum {
+			return top[i].Cum > top[j].Cum
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// FlameNode is a node in a flame graph. Children are callees of the node's
+// function.
+type FlameNode struct {
+	Name     string
+	Value    int64
+	Children []*FlameNode
+}
+
+// Fraction returns the node's share of total.
+func (n *FlameNode) Fraction(total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n.Value) / float64(total)
+}
+
+// Flame builds a flame graph, rooted at a node covering all samples. Nodes
+// smaller than minFlameFraction of the total are dropped.
+func (p *Profile) Flame(index int) *FlameNode {
+	root := &FlameNode{Name: "root"}
+
+	for _, s := range p.p.Sample {
+		v := s.Value[index]
+		if v == 0 {
+			continue
+		}
+		root.Value += v
+
+		node := root
+		stack := stackNames(s)
+		for i := len(stack) - 1; i >= 0; i-- {
+			node = node.child(stack[i])
+			node.Value += v
+		}
+	}
+
+	root.prune(int64(float64(root.Value) * minFlameFraction))
+
+	return root
+}
+
+func (n *FlameNode) child(name string) *FlameNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &FlameNode{Name: name}
+	n.Children = append(n.Children, c)
+
+	return c
+}
+
+func (n *FlameNode) prune(min int64) {
+	kept := n.Children[:0]
+	for _, c := range n.Children {
+		if c.Value < min {
+			continue
+		}
+		c.prune(min)
+		kept = append(kept, c)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Name < kept[j].Name
+	})
+	n.Children = kept
+}
+
+// Frame is a frame of a stack.
+type Frame struct {
+	Function string
+	Filename string
+	Line     int64
+}
+
+// GoroutineGroup is a set of goroutines sharing the same stack.
+type GoroutineGroup struct {
+	Count int64
+	// Stack is ordered from the innermost frame.
+	Stack  []Frame
+	Labels map[string][]string
+}
+
+// Function returns the innermost function of the stack outside the runtime,
+// which is usually where the goroutines are waiting.
+func (g GoroutineGroup) Function() string {
+	for _, f := range g.Stack {
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			return f.Function
+		}
+	}
+	if len(g.Stack) > 0 {
+		return g.Stack[0].Function
+	}
+
+	return ""
+}
+
+// Goroutines groups the goroutines in the profile by stack, largest group
+// first. Large groups parked on the same stack often point to a leak or a
+// deadlock.
+func (p *Profile) Goroutines() []GoroutineGroup {
+	index := map[string]int{}
+	var groups []GoroutineGroup
+
+	for _, s := range p.p.Sample {
+		stack := stackFrames(s)
+		key := fmt.Sprint(stack, s.Label)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, GoroutineGroup{Stack: stack, Labels: s.Label})
+		}
+		groups[i].Count += s.Value[0]
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+
+	return groups
+}
+
+// stackNames returns the function names of a sample's stack, innermost first.
+func stackNames(s *profile.Sample) []string {
+	var names []string
+	for _, loc := range s.Location {
+		// Inlined functions come first within a location.
+		for _, line := range loc.Line {
+			if line.Function != nil {
+				names = append(names, line.Function.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// stackFrames returns the frames of a sample's stack, innermost first.
+func stackFrames(s *profile.Sample) []Frame {
+	var frames []Frame
+	for _, loc := range s.Location {
+		for _, line := range loc.Line {
+			if line.Function != nil {
+				frames = append(frames, Frame{
+					Function: line.Function.Name,
+					Filename: line.Function.Filename,
+					Line:     line.Line,
+				})
+			}
+		}
+	}
+
+	return frames
+}
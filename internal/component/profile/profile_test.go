@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProfile builds a goroutine profile with the given stacks, innermost
+// frame first, and the number of goroutines sharing each.
+func newTestProfile(t *testing.T, stacks [][]string, counts []int64) *Profile {
+	t.Helper()
+
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "goroutine", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "goroutine", Unit: "count"},
+		Period:     1,
+	}
+	funcs := map[string]*profile.Function{}
+	for i, stack := range stacks {
+		s := &profile.Sample{Value: []int64{counts[i]}}
+		for _, name := range stack {
+			fn, ok := funcs[name]
+			if !ok {
+				fn = &profile.Function{ID: uint64(len(funcs) + 1), Name: name, Filename: name + ".go"}
+				funcs[name] = fn
+				p.Function = append(p.Function, fn)
+			}
+			loc := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: fn, Line: 10}}}
+			p.Location = append(p.Location, loc)
+			s.Location = append(s.Location, loc)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+
+	got, err := Parse(&buf)
+	require.NoError(t, err)
+
+	return got
+}
+
+func TestProfile_Top(t *testing.T) {
+	p := newTestProfile(t, [][]string{
+		{"b", "a", "main"},
+		{"a", "main"},
+		{"c", "main"},
+	}, []int64{3, 2, 1})
+
+	require.True(t, p.IsGoroutine())
+	index, err := p.SampleIndex("")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), p.Total(index))
+
+	got := p.Top(index, 3)
+
+	require.Equal(t, []Function{
+		{Name: "b", Flat: 3, Cum: 3},
+		{Name: "a", Flat: 2, Cum: 5},
+		{Name: "c", Flat: 1, Cum: 1},
+	}, got)
+}
+
+func TestProfile_Flame(t *testing.T) {
+	p := newTestProfile(t, [][]string{
+		{"b", "a", "main"},
+		{"a", "main"},
+		{"c", "main"},
+	}, []int64{3, 2, 1})
+
+	got := p.Flame(0)
+
+	require.Equal(t, int64(6), got.Value)
+	require.Len(t, got.Children, 1)
+	main := got.Children[0]
+	require.Equal(t, "main", main.Name)
+	require.Len(t, main.Children, 2)
+	require.Equal(t, "a", main.Children[0].Name)
+	require.Equal(t, int64(5), main.Children[0].Value)
+	require.Equal(t, "b", main.Children[0].Children[0].Name)
+	require.Equal(t, "c", main.Children[1].Name)
+}
+
+func TestProfile_Goroutines(t *testing.T) {
+	p := newTestProfile(t, [][]string{
+		{"wait", "worker"},
+		{"accept", "serve"},
+		{"wait", "worker"},
+	}, []int64{40, 1, 60})
+
+	got := p.Goroutines()
+
+	require.Len(t, got, 2)
+	require.Equal(t, int64(100), got[0].Count)
+	require.Equal(t, []Frame{
+		{Function: "wait", Filename: "wait.go", Line: 10},
+		{Function: "worker", Filename: "worker.go", Line: 10},
+	}, got[0].Stack)
+	require.Equal(t, "wait", got[0].Function())
+	require.Equal(t, int64(1), got[1].Count)
+}
+
+func TestProfile_SampleIndex(t *testing.T) {
+	p := newTestProfile(t, [][]string{{"main"}}, []int64{1})
+
+	_, err := p.SampleIndex("inuse_space")
+	require.ErrorContains(t, err, "unknown sample type")
+}
@@ -0,0 +1,210 @@
+// Package skew detects clock problems in the logs of a bundle: timestamps
+// going backwards within a file, and clocks of components drifting from the
+// agent's, estimated from correlated start events.
+package skew
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// MaxExamples is the number of out of order lines kept as examples.
+const MaxExamples = 10
+
+// MaxPairDistance is the largest time between an agent starting a component
+// and the component logging its start for the two to be paired.
+const MaxPairDistance = 10 * time.Minute
+
+// Threshold is the offset above which a clock is considered skewed, allowing
+// for the time a component takes to start.
+const Threshold = time.Second
+
+var (
+	// agentStartPattern matches agent lines starting a component.
+	agentStartPattern = regexp.MustCompile(`(?i)(spawned new component|starting (component|process)|operator: starting)`)
+	// componentStartPattern matches the first lines a component logs when it
+	// starts.
+	componentStartPattern = regexp.MustCompile(`(?i)(\bstart running\b|^starting (file|metric)beat\b)`)
+)
+
+// Monotonicity describes timestamps going backwards within a log file.
+type Monotonicity struct {
+	// OutOfOrder is the number of lines with a timestamp before the latest
+	// timestamp of the lines preceding them.
+	OutOfOrder int
+	// MaxBackward is the largest jump backwards in time.
+	MaxBackward time.Duration
+	// Examples are the indices of the first out of order lines.
+	Examples []int
+}
+
+// CheckMonotonic finds lines of a log whose timestamp goes backwards. Lines
+// without a timestamp are ignored.
+func CheckMonotonic(logCtx *logs.Context) Monotonicity {
+	var m Monotonicity
+	var latest time.Time
+
+	for i := 0; i < logCtx.Lines(); i++ {
+		ts, ok := logCtx.Timestamp(i)
+		if !ok {
+			continue
+		}
+		if !ts.Before(latest) {
+			latest = ts
+			continue
+		}
+
+		m.OutOfOrder++
+		if d := latest.Sub(ts); d > m.MaxBackward {
+			m.MaxBackward = d
+		}
+		if len(m.Examples) < MaxExamples {
+			m.Examples = append(m.Examples, i)
+		}
+	}
+
+	return m
+}
+
+// Event is a component starting, as logged by the agent or the component.
+type Event struct {
+	// Binary is the binary of the component, such as "filebeat".
+	Binary string
+	Index  int
+	Time   time.Time
+}
+
+// Binary returns the binary writing logs of a component, or an empty string
+// for logs not written by a component run by the agent.
+func Binary(component logs.Component) string {
+	switch component {
+	case logs.ComponentFilebeat, logs.ComponentMetricbeat:
+		return strings.ToLower(component.String())
+	}
+
+	return ""
+}
+
+// binaries are the binaries of components which have their own logs.
+var binaries = []string{Binary(logs.ComponentFilebeat), Binary(logs.ComponentMetricbeat)}
+
+// AgentEvents finds the lines of an agent log starting a component. The
+// component is taken from the component.binary field, or else from the
+// first binary named in the message or component.id.
+func AgentEvents(logCtx *logs.Context) []Event {
+	var events []Event
+	for i, line := range logCtx.ViewAll() {
+		message, _ := line.GetString("message")
+		if !agentStartPattern.MatchString(message) {
+			continue
+		}
+		ts, ok := logCtx.Timestamp(i)
+		if !ok {
+			continue
+		}
+
+		binary, _ := line.GetString("component.binary")
+		if binary == "" {
+			id, _ := line.GetString("component.id")
+			text := strings.ToLower(message + " " + id)
+			for _, b := range binaries {
+				if strings.Contains(text, b) {
+					binary = b
+					break
+				}
+			}
+		}
+		if binary != "" {
+			events = append(events, Event{Binary: binary, Index: i, Time: ts})
+		}
+	}
+
+	return events
+}
+
+// ComponentEvents finds the lines of a component's own log written when it
+// started.
+func ComponentEvents(logCtx *logs.Context, binary string) []Event {
+	var events []Event
+	for i, line := range logCtx.ViewAll() {
+		message, _ := line.GetString("message")
+		if !componentStartPattern.MatchString(message) {
+			continue
+		}
+		if ts, ok := logCtx.Timestamp(i); ok {
+			events = append(events, Event{Binary: binary, Index: i, Time: ts})
+		}
+	}
+
+	return events
+}
+
+// Estimate is the estimated clock offset of a component log file.
+type Estimate struct {
+	Filename string
+	Binary   string
+	// Offset is how far the file's clock is ahead of the agent's. Negative
+	// offsets are behind.
+	Offset time.Duration
+	// Samples is the number of start events paired with the agent's.
+	Samples int
+}
+
+// Skewed returns true if the offset exceeds Threshold in either direction.
+func (e Estimate) Skewed() bool {
+	return e.Offset > Threshold || e.Offset < -Threshold
+}
+
+// EstimateOffset pairs each start event of a component file with the nearest
+// agent event starting the same binary within MaxPairDistance, and estimates
+// the offset of the file's clock as the median difference. False is returned
+// if no events could be paired.
+func EstimateOffset(filename string, agent, component []Event) (Estimate, bool) {
+	var diffs []time.Duration
+	for _, c := range component {
+		var best time.Duration
+		found := false
+		for _, a := range agent {
+			if a.Binary != c.Binary {
+				continue
+			}
+			d := c.Time.Sub(a.Time)
+			if abs(d) > MaxPairDistance {
+				continue
+			}
+			if !found || abs(d) < abs(best) {
+				best, found = d, true
+			}
+		}
+		if found {
+			diffs = append(diffs, best)
+		}
+	}
+	if len(diffs) == 0 {
+		return Estimate{}, false
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i] < diffs[j] })
+	est := Estimate{
+		Filename: filename,
+		Binary:   component[0].Binary,
+		Samples:  len(diffs),
+		Offset:   diffs[len(diffs)/2],
+	}
+	if len(diffs)%2 == 0 {
+		est.Offset = (diffs[len(diffs)/2-1] + diffs[len(diffs)/2]) / 2
+	}
+
+	return est, true
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
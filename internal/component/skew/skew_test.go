@@ -0,0 +1,81 @@
+package skew
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+func newTestContext(t *testing.T, lines ...string) *logs.Context {
+	t.Helper()
+
+	c := logs.NewContext(logs.DefaultContextConfig())
+	for _, line := range lines {
+		fields := collections.Fields{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		c.AddLine(fields)
+	}
+	c.Analyze()
+
+	return c
+}
+
+func TestCheckMonotonic(t *testing.T) {
+	c := newTestContext(t,
+		`{"@timestamp":"2023-01-04T22:53:00Z","message":"one"}`,
+		`{"@timestamp":"2023-01-04T22:53:10Z","message":"two"}`,
+		`{"@timestamp":"2023-01-04T22:53:05Z","message":"back 5s"}`,
+		`{"message":"no timestamp"}`,
+		`{"@timestamp":"2023-01-04T22:52:50Z","message":"back 20s"}`,
+		`{"@timestamp":"2023-01-04T22:53:10Z","message":"same"}`,
+		`{"@timestamp":"2023-01-04T22:53:11Z","message":"forward"}`,
+	)
+
+	require.Equal(t, Monotonicity{
+		OutOfOrder:  2,
+		MaxBackward: 20 * time.Second,
+		Examples:    []int{2, 4},
+	}, CheckMonotonic(c))
+}
+
+func TestEstimateOffset(t *testing.T) {
+	agent := newTestContext(t,
+		`{"@timestamp":"2023-01-04T22:00:00Z","message":"Spawned new component filebeat-default: Starting: spawned pid '100'"}`,
+		`{"@timestamp":"2023-01-04T22:00:01Z","message":"Spawned new component system/metrics-default: Starting","component":{"binary":"metricbeat"}}`,
+		`{"@timestamp":"2023-01-04T23:00:00Z","message":"Starting component","component":{"id":"filebeat-monitoring"}}`,
+		`{"@timestamp":"2023-01-04T23:00:05Z","message":"filebeat is healthy"}`,
+	)
+	filebeat := newTestContext(t,
+		`{"@timestamp":"2023-01-04T22:00:30.5Z","message":"filebeat start running."}`,
+		`{"@timestamp":"2023-01-04T22:00:40Z","message":"Starting harvester"}`,
+		`{"@timestamp":"2023-01-04T23:00:29.5Z","message":"filebeat start running."}`,
+		// Too far from any agent event.
+		`{"@timestamp":"2023-01-05T10:00:00Z","message":"filebeat start running."}`,
+	)
+
+	agentEvents := AgentEvents(agent)
+	require.Len(t, agentEvents, 3)
+	require.Equal(t, []string{"filebeat", "metricbeat", "filebeat"}, []string{agentEvents[0].Binary, agentEvents[1].Binary, agentEvents[2].Binary})
+
+	componentEvents := ComponentEvents(filebeat, Binary(logs.ComponentFilebeat))
+	require.Len(t, componentEvents, 3)
+
+	est, ok := EstimateOffset("filebeat.ndjson", agentEvents, componentEvents)
+	require.True(t, ok)
+	require.Equal(t, Estimate{
+		Filename: "filebeat.ndjson",
+		Binary:   "filebeat",
+		Offset:   30 * time.Second,
+		Samples:  2,
+	}, est)
+	require.True(t, est.Skewed())
+
+	// Filebeat's start events don't pair with the agent starting metricbeat.
+	_, ok = EstimateOffset("metricbeat.ndjson", agentEvents[1:2], componentEvents)
+	require.False(t, ok)
+}
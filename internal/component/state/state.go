@@ -0,0 +1,178 @@
+// Package state parses the agent state file found in diagnostics bundles.
+package state
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Filename is the name of the agent state file in a bundle.
+const Filename = "state.yaml"
+
+// State is the state of the agent, a component or a unit. The numeric values
+// match those used by the agent.
+type State int
+
+const (
+	StateStarting State = iota
+	StateConfiguring
+	StateHealthy
+	StateDegraded
+	StateFailed
+	StateStopping
+	StateStopped
+	StateUpgrading
+	StateRollback
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "STARTING"
+	case StateConfiguring:
+		return "CONFIGURING"
+	case StateHealthy:
+		return "HEALTHY"
+	case StateDegraded:
+		return "DEGRADED"
+	case StateFailed:
+		return "FAILED"
+	case StateStopping:
+		return "STOPPING"
+	case StateStopped:
+		return "STOPPED"
+	case StateUpgrading:
+		return "UPGRADING"
+	case StateRollback:
+		return "ROLLBACK"
+	}
+
+	return ""
+}
+
+// UnmarshalYAML decodes a state from either its numeric value or its name, as
+// different agent versions write one or the other.
+func (s *State) UnmarshalYAML(value *yaml.Node) error {
+	if n, err := strconv.Atoi(value.Value); err == nil {
+		if State(n).String() == "" {
+			return fmt.Errorf("unknown state: %d", n)
+		}
+		*s = State(n)
+		return nil
+	}
+
+	for v := StateStarting; v <= StateRollback; v++ {
+		if strings.EqualFold(value.Value, v.String()) {
+			*s = v
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown state: %q", value.Value)
+}
+
+// IsProblem returns true if the state needs attention.
+func (s State) IsProblem() bool {
+	return s == StateDegraded || s == StateFailed
+}
+
+// AgentState is the state of the agent and all of its components.
+type AgentState struct {
+	State        State       `yaml:"state"`
+	Message      string      `yaml:"message"`
+	FleetState   State       `yaml:"fleet_state"`
+	FleetMessage string      `yaml:"fleet_message"`
+	LogLevel     string      `yaml:"log_level"`
+	Components   []Component `yaml:"components"`
+}
+
+// Component is a component run by the agent, such as a Beat.
+type Component struct {
+	ID    string         `yaml:"id"`
+	State ComponentState `yaml:"state"`
+}
+
+// ComponentState is the reported state of a component.
+type ComponentState struct {
+	State       State           `yaml:"state"`
+	Message     string          `yaml:"message"`
+	Units       map[string]Unit `yaml:"units"`
+	VersionInfo VersionInfo     `yaml:"version_info"`
+}
+
+// Unit is an input or output unit run by a component.
+type Unit struct {
+	State   State  `yaml:"state"`
+	Message string `yaml:"message"`
+}
+
+// VersionInfo describes the version of a component.
+type VersionInfo struct {
+	Name      string            `yaml:"name"`
+	Version   string            `yaml:"version"`
+	BuildHash string            `yaml:"build_hash"`
+	Meta      map[string]string `yaml:"meta"`
+}
+
+// NamedUnit is a unit along with its key and type.
+type NamedUnit struct {
+	Unit
+	// Key is the unit's key in the state file, prefixed by its type.
+	Key string
+	// Type is the type of the unit, "input" or "output".
+	Type string
+}
+
+// SortedUnits returns the units of the component ordered by key.
+func (c Component) SortedUnits() []NamedUnit {
+	units := make([]NamedUnit, 0, len(c.State.Units))
+	for k, v := range c.State.Units {
+		unitType, _, _ := strings.Cut(k, "-")
+		units = append(units, NamedUnit{Unit: v, Key: k, Type: unitType})
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].Key < units[j].Key
+	})
+
+	return units
+}
+
+// Worst returns the worst state of the component and its units. Failed is worse
+// than degraded, which is worse than any other state.
+func (c Component) Worst() State {
+	worst := c.State.State
+	for _, u := range c.State.Units {
+		if rank(u.State) > rank(worst) {
+			worst = u.State
+		}
+	}
+
+	return worst
+}
+
+func rank(s State) int {
+	switch s {
+	case StateFailed:
+		return 2
+	case StateDegraded:
+		return 1
+	}
+
+	return 0
+}
+
+// Parse parses an agent state file.
+func Parse(r io.Reader) (AgentState, error) {
+	var state AgentState
+
+	if err := yaml.NewDecoder(r).Decode(&state); err != nil {
+		return AgentState{}, fmt.Errorf("unable to decode agent state: %w", err)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "state.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	got, err := Parse(f)
+	require.NoError(t, err)
+
+	require.Equal(t, StateHealthy, got.State)
+	require.Equal(t, "Running", got.Message)
+	require.Equal(t, StateHealthy, got.FleetState)
+	require.Equal(t, "info", got.LogLevel)
+	require.Len(t, got.Components, 2)
+
+	filestream := got.Components[0]
+	require.Equal(t, "filestream-default", filestream.ID)
+	require.Equal(t, StateHealthy, filestream.State.State)
+	require.Equal(t, "8.6.0", filestream.State.VersionInfo.Version)
+	require.Equal(t, StateFailed, filestream.Worst())
+
+	units := filestream.SortedUnits()
+	require.Len(t, units, 3)
+	require.Equal(t, "input-filestream-default-filestream-system-1", units[1].Key)
+	require.Equal(t, "input", units[1].Type)
+	require.Equal(t, StateFailed, units[1].State)
+	require.Equal(t, "output", units[2].Type)
+
+	metrics := got.Components[1]
+	require.Equal(t, StateDegraded, metrics.State.State)
+	require.Equal(t, StateDegraded, metrics.Worst())
+}
+
+func TestParse_UnknownState(t *testing.T) {
+	_, err := Parse(strings.NewReader("state: 42\n"))
+	require.ErrorContains(t, err, "unknown state")
+
+	_, err = Parse(strings.NewReader("state: SLEEPING\n"))
+	require.ErrorContains(t, err, "unknown state")
+}
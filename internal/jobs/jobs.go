@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxPreview is the maximum number of preview lines kept by a job.
+	MaxPreview = 100
+	// Retention is how long finished jobs are kept for their progress to be
+	// queried.
+	Retention = 10 * time.Minute
+)
+
+// ErrClosed is returned by jobs which were cancelled because the manager was
+// closed.
+var ErrClosed = errors.New("job manager closed")
+
+// Status is the status of a job.
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusRunning
+	StatusDone
+	StatusFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "Pending"
+	case StatusRunning:
+		return "Running"
+	case StatusDone:
+		return "Done"
+	case StatusFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Progress is the progress of a job, in units of work such as bytes read.
+type Progress struct {
+	Current int64
+	Total   int64
+}
+
+// Percent returns the completed percentage, or 0 if the total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	if p.Current >= p.Total {
+		return 100
+	}
+
+	return float64(p.Current) / float64(p.Total) * 100
+}
+
+// Func performs the work of a job, reporting its progress to j.
+type Func func(ctx context.Context, j *Job) (any, error)
+
+// Job is a unit of work run in the background by a Manager.
+type Job struct {
+	ID   string
+	Name string
+
+	key      string
+	status   Status
+	progress Progress
+	preview  []string
+	result   any
+	err      error
+	finished time.Time
+	done     chan struct{}
+	mu       sync.RWMutex
+}
+
+// Key returns the key the job was submitted with.
+func (j *Job) Key() string {
+	return j.key
+}
+
+// Status returns the status of the job.
+func (j *Job) Status() Status {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.status
+}
+
+// Progress returns the progress of the job.
+func (j *Job) Progress() Progress {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.progress
+}
+
+// Preview returns the partial results collected while the job is running.
+func (j *Job) Preview() []string {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	preview := make([]string, len(j.preview))
+	copy(preview, j.preview)
+
+	return preview
+}
+
+// Result returns the result and error of the job. Both are nil until the job
+// has finished.
+func (j *Job) Result() (any, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.result, j.err
+}
+
+// Done returns a channel which is closed when the job has finished.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to finish and returns its result.
+func (j *Job) Wait(ctx context.Context) (any, error) {
+	select {
+	case <-j.done:
+		return j.Result()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SetTotal sets the total amount of work of the job.
+func (j *Job) SetTotal(total int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.progress.Total = total
+}
+
+// Add adds to the amount of completed work of the job.
+func (j *Job) Add(n int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.progress.Current += n
+}
+
+// AddPreview adds partial results to the job. Lines beyond MaxPreview are
+// dropped.
+func (j *Job) AddPreview(lines ...string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if n := MaxPreview - len(j.preview); n < len(lines) {
+		lines = lines[:n]
+	}
+	j.preview = append(j.preview, lines...)
+}
+
+func (j *Job) setStatus(status Status) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status = status
+}
+
+func (j *Job) finish(result any, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.result, j.err = result, err
+	j.status = StatusDone
+	if err != nil {
+		j.status = StatusFailed
+	}
+	j.finished = time.Now()
+	close(j.done)
+}
+
+func (j *Job) finishedBefore(t time.Time) bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return !j.finished.IsZero() && j.finished.Before(t)
+}
+
+// Manager runs jobs in the background with a bounded number of workers.
+type Manager struct {
+	sem    chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	jobs map[string]*Job
+	keys map[string]*Job
+	mu   sync.Mutex
+}
+
+// Submit starts a job running fn. If a job with the same key is still pending
+// or running, that job is returned instead.
+func (m *Manager) Submit(key, name string, fn Func) *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if j, ok := m.keys[key]; ok {
+		return j
+	}
+	m.prune()
+
+	j := &Job{
+		ID:   uuid.NewString(),
+		Name: name,
+		key:  key,
+		done: make(chan struct{}),
+	}
+	m.jobs[j.ID] = j
+	m.keys[key] = j
+
+	m.wg.Add(1)
+	go m.run(j, fn)
+
+	return j
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+
+	return j, ok
+}
+
+// Close cancels all jobs and waits for them to finish.
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) run(j *Job, fn Func) {
+	defer m.wg.Done()
+
+	var result any
+	var err error
+
+	select {
+	case m.sem <- struct{}{}:
+		if err = m.ctx.Err(); err != nil {
+			err = ErrClosed
+		} else {
+			j.setStatus(StatusRunning)
+			result, err = fn(m.ctx, j)
+		}
+		<-m.sem
+	case <-m.ctx.Done():
+		err = ErrClosed
+	}
+
+	m.mu.Lock()
+	delete(m.keys, j.key)
+	m.mu.Unlock()
+
+	j.finish(result, err)
+}
+
+// prune removes jobs which finished longer than Retention ago. The caller must
+// hold the lock.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-Retention)
+	for id, j := range m.jobs {
+		if j.finishedBefore(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// NewManager creates a Manager running at most workers jobs at a time.
+func NewManager(workers int) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		sem:    make(chan struct{}, workers),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   map[string]*Job{},
+		keys:   map[string]*Job{},
+	}
+}
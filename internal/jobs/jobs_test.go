@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Submit(t *testing.T) {
+	m := NewManager(2)
+	defer m.Close()
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fn := func(ctx context.Context, j *Job) (any, error) {
+		calls.Add(1)
+		<-release
+		return "done", nil
+	}
+
+	a := m.Submit("key", "a", fn)
+	b := m.Submit("key", "b", fn)
+	require.Same(t, a, b)
+	require.Equal(t, "key", a.Key())
+
+	got, ok := m.Get(a.ID)
+	require.True(t, ok)
+	require.Same(t, a, got)
+
+	close(release)
+	result, err := a.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "done", result)
+	require.Equal(t, StatusDone, a.Status())
+	require.EqualValues(t, 1, calls.Load())
+
+	// A finished job is not reused.
+	c := m.Submit("key", "c", func(ctx context.Context, j *Job) (any, error) {
+		return nil, errors.New("failed")
+	})
+	require.NotSame(t, a, c)
+	_, err = c.Wait(context.Background())
+	require.EqualError(t, err, "failed")
+	require.Equal(t, StatusFailed, c.Status())
+}
+
+func TestManager_Workers(t *testing.T) {
+	const workers = 2
+
+	m := NewManager(workers)
+	defer m.Close()
+
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	fn := func(ctx context.Context, j *Job) (any, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil, nil
+	}
+
+	var submitted []*Job
+	for i := 0; i < 5; i++ {
+		submitted = append(submitted, m.Submit(fmt.Sprint(i), "job", fn))
+	}
+	require.Eventually(t, func() bool { return running.Load() == workers }, time.Second, time.Millisecond)
+
+	pending := 0
+	for _, j := range submitted {
+		if j.Status() == StatusPending {
+			pending++
+		}
+	}
+	require.Equal(t, 3, pending)
+
+	close(release)
+	for _, j := range submitted {
+		_, err := j.Wait(context.Background())
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, workers, peak.Load())
+}
+
+func TestManager_Close(t *testing.T) {
+	m := NewManager(1)
+
+	j := m.Submit("key", "job", func(ctx context.Context, j *Job) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.Eventually(t, func() bool { return j.Status() == StatusRunning }, time.Second, time.Millisecond)
+	pending := m.Submit("other", "job", func(ctx context.Context, j *Job) (any, error) {
+		return nil, nil
+	})
+
+	m.Close()
+
+	_, err := j.Result()
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = pending.Result()
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestReader(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < MaxPreview+10; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	data := sb.String()
+
+	j := &Job{done: make(chan struct{})}
+	j.SetTotal(int64(len(data)))
+
+	// Read in small chunks so lines are split across reads.
+	r := NewReader(context.Background(), j, strings.NewReader(data))
+	buf := make([]byte, 7)
+	for {
+		if _, err := r.Read(buf); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	require.Equal(t, Progress{Current: int64(len(data)), Total: int64(len(data))}, j.Progress())
+	require.Equal(t, 100.0, j.Progress().Percent())
+	require.Empty(t, j.Preview())
+}
+
+func TestPreviewReader(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < MaxPreview+10; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+
+	j := &Job{done: make(chan struct{})}
+
+	// Read in small chunks so lines are split across reads.
+	r := NewPreviewReader(j, strings.NewReader(sb.String()))
+	buf := make([]byte, 7)
+	for {
+		if _, err := r.Read(buf); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	require.Equal(t, int64(0), j.Progress().Current)
+
+	preview := j.Preview()
+	require.Len(t, preview, MaxPreview)
+	require.Equal(t, "line 0", preview[0])
+	require.Equal(t, fmt.Sprintf("line %d", MaxPreview-1), preview[MaxPreview-1])
+}
+
+func TestReader_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewReader(ctx, &Job{done: make(chan struct{})}, strings.NewReader("data"))
+	_, err := r.Read(make([]byte, 4))
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// maxPreviewLine is the maximum length of a preview line. Longer lines are
+// truncated.
+const maxPreviewLine = 4096
+
+// Reader reports the bytes read from an underlying reader as the progress of a
+// job. Reads fail once the context is cancelled.
+type Reader struct {
+	ctx context.Context
+	job *Job
+	r   io.Reader
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.job.Add(int64(n))
+	}
+
+	return n, err
+}
+
+// NewReader creates a Reader reporting progress to job.
+func NewReader(ctx context.Context, job *Job, r io.Reader) *Reader {
+	return &Reader{
+		ctx: ctx,
+		job: job,
+		r:   r,
+	}
+}
+
+// PreviewReader collects the first lines read from an underlying reader as the
+// preview of a job. It is separate from Reader, so progress can be reported for
+// the bytes of a compressed file while the preview shows its content.
+type PreviewReader struct {
+	job     *Job
+	r       io.Reader
+	partial []byte
+	lines   int
+}
+
+func (r *PreviewReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.collect(p[:n])
+	}
+
+	return n, err
+}
+
+// collect adds complete lines from data to the job's preview until MaxPreview
+// lines have been collected.
+func (r *PreviewReader) collect(data []byte) {
+	if r.lines >= MaxPreview {
+		return
+	}
+
+	var lines []string
+	for r.lines+len(lines) < MaxPreview {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			r.appendPartial(data)
+			break
+		}
+		r.appendPartial(data[:i])
+		lines = append(lines, string(bytes.TrimSuffix(r.partial, []byte{'\r'})))
+		r.partial = r.partial[:0]
+		data = data[i+1:]
+	}
+	r.lines += len(lines)
+
+	r.job.AddPreview(lines...)
+}
+
+func (r *PreviewReader) appendPartial(data []byte) {
+	if n := maxPreviewLine - len(r.partial); n < len(data) {
+		data = data[:n]
+	}
+	r.partial = append(r.partial, data...)
+}
+
+// NewPreviewReader creates a PreviewReader adding lines to the preview of job.
+func NewPreviewReader(job *Job, r io.Reader) *PreviewReader {
+	return &PreviewReader{
+		job: job,
+		r:   r,
+	}
+}
@@ -42,7 +42,6 @@ func (f *stackFormatter) write(m string, a ...interface{}) {
 
 func (f *stackFormatter) format(p interface{}, debugStack []byte) ([]byte, error) {
 	var lines []string
-	var err error
 
 	f.buf.Reset()
 
@@ -69,16 +68,33 @@ func (f *stackFormatter) format(p interface{}, debugStack []byte) ([]byte, error
 		lines[i], lines[opp] = lines[opp], lines[i]
 	}
 
-	// decorate
+	if err := f.formatLines(lines); err != nil {
+		return nil, err
+	}
+
+	return f.buf.Bytes(), nil
+}
+
+// formatLines decorates the lines of a stack, the innermost frame first.
+func (f *stackFormatter) formatLines(lines []string) error {
 	for i, line := range lines {
-		lines[i], err = f.decorateLine(line, i)
+		decorated, err := f.decorateLine(line, i)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		f.buf.WriteString(decorated)
 	}
 
-	for _, l := range lines {
-		f.write(l)
+	return nil
+}
+
+// FormatStack decorates the lines of a goroutine stack with colors, marking the
+// innermost frame. Lines alternate between function calls and source locations,
+// as in a goroutine dump.
+func FormatStack(stack []string) ([]byte, error) {
+	f := newStackFormatter()
+	if err := f.formatLines(stack); err != nil {
+		return nil, err
 	}
 
 	return f.buf.Bytes(), nil
@@ -0,0 +1,199 @@
+// Package redact provides functions for removing secrets from configs and logs.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+// sensitiveKeyPattern matches keys which are likely to hold secrets.
+const sensitiveKeyPattern = `(?i:password|passwd|passphrase|api[_-]?key|secret|token|private[_-]?key|authorization|credential)`
+
+var (
+	sensitiveKeyRegex = regexp.MustCompile(sensitiveKeyPattern)
+
+	// yamlValueRegex matches "key: value" pairs in YAML.
+	yamlValueRegex = regexp.MustCompile(`^(\s*(?:-\s+)?["']?[^\s:"']*` + sensitiveKeyPattern + `[^\s:"']*["']?\s*:\s+)(\S.*)$`)
+	// jsonValueRegex matches "key":"value" pairs in JSON.
+	jsonValueRegex = regexp.MustCompile(`("[^"]*` + sensitiveKeyPattern + `[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// keyValueRegex matches key=value pairs in text, such as query strings.
+	keyValueRegex = regexp.MustCompile(`(\b[\w.-]*` + sensitiveKeyPattern + `[\w.-]*=)[^\s&"',]+`)
+	// urlCredentialsRegex matches credentials embedded in URLs.
+	urlCredentialsRegex = regexp.MustCompile(`(://)[^/\s:@"]+:[^/\s@"]+@`)
+	// authHeaderRegex matches authorization header values.
+	authHeaderRegex = regexp.MustCompile(`(?i)\b(ApiKey|Bearer|Basic)\s+[A-Za-z0-9+/=._~-]{8,}`)
+	// privateKeyRegex matches PEM encoded private keys, including escaped
+	// newlines and keys cut off before their end marker.
+	privateKeyRegex = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----(?s:.*?)(?:-----END [A-Z0-9 ]*PRIVATE KEY-----|$)`)
+)
+
+// IsSensitiveKey returns true if values of key are likely to be secrets. The
+// key may be a dotted path, such as ssl.key, which is sensitive because the
+// key under ssl may hold a private key inline.
+func IsSensitiveKey(key string) bool {
+	if sensitiveKeyRegex.MatchString(key) {
+		return true
+	}
+
+	parts := strings.Split(key, ".")
+	last := len(parts) - 1
+
+	return parts[last] == "key" && slices.Contains(parts[:last], "ssl")
+}
+
+// joinKey returns the dotted path of key under the parent path.
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// Line redacts secrets in a single line of YAML, JSON or plain text.
+func Line(line string) string {
+	line = privateKeyRegex.ReplaceAllString(line, Placeholder)
+	line = yamlValueRegex.ReplaceAllString(line, "${1}"+Placeholder)
+	line = jsonValueRegex.ReplaceAllString(line, `${1}"`+Placeholder+`"`)
+	line = keyValueRegex.ReplaceAllString(line, "${1}"+Placeholder)
+	line = urlCredentialsRegex.ReplaceAllString(line, "${1}"+Placeholder+"@")
+	line = authHeaderRegex.ReplaceAllString(line, "${1} "+Placeholder)
+
+	return line
+}
+
+// Text redacts secrets in each line of s.
+func Text(s string) string {
+	// Private keys span several lines, so they are removed first.
+	s = privateKeyRegex.ReplaceAllString(s, Placeholder)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = Line(line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Value redacts secrets in a value decoded from JSON. Values of sensitive keys
+// are replaced as a whole, including nested objects and arrays, and other
+// strings are redacted as text.
+func Value(v any) any {
+	return value(v, "")
+}
+
+// value redacts secrets in v, which is found at the dotted path.
+func value(v any, path string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if IsSensitiveKey(joinKey(path, key)) {
+				v[key] = Placeholder
+			} else {
+				v[key] = value(child, joinKey(path, key))
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = value(child, path)
+		}
+	case string:
+		return Text(v)
+	}
+
+	return v
+}
+
+// JSON redacts secrets in a JSON document, returning it in compact form.
+func JSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after top-level value")
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(Value(v)); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// YAML redacts secrets in a YAML stream of one or more documents. Values of
+// sensitive keys are replaced as a whole, including block scalars and nested
+// mappings, and other strings are redacted as text.
+func YAML(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		yamlNode(&doc, "")
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// yamlNode redacts secrets in n and its children. The path is the dotted path
+// of n in the document.
+func yamlNode(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range n.Content {
+			yamlNode(child, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			keyPath := joinKey(path, key.Value)
+			if IsSensitiveKey(keyPath) {
+				// The anchor is kept so aliases of the value stay valid.
+				*value = yaml.Node{
+					Kind:        yaml.ScalarNode,
+					Tag:         "!!str",
+					Value:       Placeholder,
+					Anchor:      value.Anchor,
+					HeadComment: value.HeadComment,
+				}
+				continue
+			}
+			yamlNode(value, keyPath)
+		}
+	case yaml.ScalarNode:
+		if n.ShortTag() == "!!str" {
+			n.Value = Text(n.Value)
+		}
+	}
+}
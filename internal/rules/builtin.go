@@ -0,0 +1,13 @@
+package rules
+
+import (
+	"embed"
+)
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// Builtin returns the rules shipped with Sawmill.
+func Builtin() ([]*Rule, error) {
+	return LoadFS(builtinFS, "builtin")
+}
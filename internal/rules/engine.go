@@ -0,0 +1,382 @@
+package rules
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	"github.com/taylor-swanson/sawmill/internal/collections"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// MaxEvidence is the maximum number of evidence entries kept per finding.
+const MaxEvidence = 20
+
+// Evidence references the data that caused a rule to fire.
+type Evidence struct {
+	Filename string
+	// Line is the index of the matching log line, or -1 for documents.
+	Line int
+	// Field is the field or document path that matched.
+	Field string
+	// Value is the matched value, or the log message for log lines.
+	Value string
+}
+
+// IsLogLine returns true if the evidence refers to a log line.
+func (e Evidence) IsLogLine() bool {
+	return e.Line >= 0
+}
+
+// Finding is a rule which fired for a bundle.
+type Finding struct {
+	Rule     *Rule
+	Evidence []Evidence
+	// Total is the number of matches found, which may exceed len(Evidence).
+	Total int
+}
+
+func (f *Finding) add(e Evidence) {
+	f.Total++
+	if len(f.Evidence) < MaxEvidence {
+		f.Evidence = append(f.Evidence, e)
+	}
+}
+
+// LogLoaderFunc returns the parsed log context for a log file in a bundle.
+type LogLoaderFunc func(filename string) (*logs.Context, error)
+
+// Evaluate runs rules against viewer, returning the findings ordered by severity,
+// most severe first. Files which can't be read are skipped and their errors
+// returned alongside any findings.
+func Evaluate(rules []*Rule, viewer bundle.Viewer, loadLog LogLoaderFunc) ([]Finding, error) {
+	var errs error
+
+	e := evaluator{
+		viewer:  viewer,
+		loadLog: loadLog,
+		docs:    map[string]any{},
+	}
+
+	var findings []Finding
+	for _, rule := range rules {
+		finding := Finding{Rule: rule}
+
+		for _, m := range rule.Logs {
+			errs = multierr.Append(errs, e.matchLogs(&finding, m))
+		}
+		for _, m := range rule.Configs {
+			errs = multierr.Append(errs, e.matchDocuments(&finding, m, e.configFiles()))
+		}
+		for _, m := range rule.Files {
+			errs = multierr.Append(errs, e.matchDocuments(&finding, m, e.allFiles()))
+		}
+
+		if finding.Total > 0 {
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Rule.Severity != findings[j].Rule.Severity {
+			return findings[i].Rule.Severity > findings[j].Rule.Severity
+		}
+		return findings[i].Rule.ID < findings[j].Rule.ID
+	})
+
+	return findings, errs
+}
+
+type evaluator struct {
+	viewer  bundle.Viewer
+	loadLog LogLoaderFunc
+	// docs caches parsed documents by filename.
+	docs  map[string]any
+	files []string
+}
+
+func (e *evaluator) matchLogs(finding *Finding, m LogMatcher) error {
+	var errs error
+
+	for _, entry := range e.viewer.GetLogs() {
+		if !matchFiles(m.Files, path.Base(entry.Filename)) {
+			continue
+		}
+		if m.Component != "" && !strings.EqualFold(m.Component, entry.Component.String()) {
+			continue
+		}
+
+		logCtx, err := e.loadLog(entry.Filename)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+
+		for i, line := range logCtx.ViewAll() {
+			if !matchLine(line, m.Conditions) {
+				continue
+			}
+			message, _ := line.GetString("message")
+			finding.add(Evidence{
+				Filename: entry.Filename,
+				Line:     i,
+				Field:    m.Conditions[0].Field,
+				Value:    message,
+			})
+		}
+	}
+
+	return errs
+}
+
+func (e *evaluator) matchDocuments(finding *Finding, m DocumentMatcher, files []string) error {
+	var errs error
+
+	for _, filename := range files {
+		if !matchFiles(m.Files, filename) && !matchFiles(m.Files, path.Base(filename)) {
+			continue
+		}
+
+		doc, err := e.document(filename)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+
+		var evidence []Evidence
+		matched := true
+		for _, c := range m.Conditions {
+			values := lookup(doc, c.Field, "")
+			if c.Exists != nil && !*c.Exists {
+				if len(values) != 0 {
+					matched = false
+					break
+				}
+				continue
+			}
+
+			found := false
+			for _, v := range values {
+				if c.match(v.value, true) {
+					found = true
+					evidence = append(evidence, Evidence{
+						Filename: filename,
+						Line:     -1,
+						Field:    v.path,
+						Value:    stringValue(v.value),
+					})
+				}
+			}
+			if !found {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, v := range evidence {
+			finding.add(v)
+		}
+	}
+
+	return errs
+}
+
+// document returns the parsed YAML or JSON content of a file.
+func (e *evaluator) document(filename string) (any, error) {
+	if doc, ok := e.docs[filename]; ok {
+		return doc, nil
+	}
+
+	f, err := e.viewer.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %q: %w", filename, err)
+	}
+
+	var doc any
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		// Not every matched file is a document, these simply never match.
+		doc = nil
+	}
+	e.docs[filename] = doc
+
+	return doc, nil
+}
+
+func (e *evaluator) configFiles() []string {
+	configs := e.viewer.GetConfigs()
+
+	files := make([]string, 0, len(configs))
+	for _, v := range configs {
+		files = append(files, v.Filename)
+	}
+
+	return files
+}
+
+func (e *evaluator) allFiles() []string {
+	if e.files != nil {
+		return e.files
+	}
+
+	e.files = []string{}
+	_ = e.viewer.Walk("", func(file *zip.File) error {
+		if !file.FileInfo().IsDir() {
+			e.files = append(e.files, file.Name)
+		}
+		return nil
+	})
+
+	return e.files
+}
+
+func matchFiles(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+
+	return ok
+}
+
+func matchLine(line collections.Fields, conditions []Condition) bool {
+	for _, c := range conditions {
+		value, ok := line.Get(c.Field)
+		if !c.match(value, ok) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match returns true if value satisfies the condition. Exists is false if the
+// field was not found.
+func (c *Condition) match(value any, exists bool) bool {
+	if c.Exists != nil && *c.Exists != exists {
+		return false
+	}
+	if !exists {
+		return c.Exists != nil
+	}
+
+	str := stringValue(value)
+	if c.Equals != "" && !strings.EqualFold(str, c.Equals) {
+		return false
+	}
+	if c.Contains != "" && !strings.Contains(strings.ToLower(str), strings.ToLower(c.Contains)) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(str) {
+		return false
+	}
+
+	return true
+}
+
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]any, map[any]any, []any, collections.Fields:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// docValue is a value found in a document, along with its concrete path.
+type docValue struct {
+	path  string
+	value any
+}
+
+// lookup finds all values at field in v. Keys may contain dots, and a "*"
+// segment matches every element of a list or map.
+func lookup(v any, field, prefix string) []docValue {
+	if field == "" {
+		return []docValue{{path: prefix, value: v}}
+	}
+
+	seg, rest, _ := strings.Cut(field, ".")
+
+	switch node := v.(type) {
+	case map[string]any:
+		var values []docValue
+		if seg == "*" {
+			keys := make([]string, 0, len(node))
+			for k := range node {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				values = append(values, lookup(node[k], rest, joinPath(prefix, k))...)
+			}
+			return values
+		}
+		for i := len(field); i > 0; i-- {
+			if i != len(field) && field[i] != '.' {
+				continue
+			}
+			child, ok := node[field[:i]]
+			if !ok {
+				continue
+			}
+			var remaining string
+			if i < len(field) {
+				remaining = field[i+1:]
+			}
+			values = append(values, lookup(child, remaining, joinPath(prefix, field[:i]))...)
+		}
+		return values
+	case map[any]any:
+		converted := make(map[string]any, len(node))
+		for k, child := range node {
+			converted[fmt.Sprintf("%v", k)] = child
+		}
+		return lookup(converted, field, prefix)
+	case []any:
+		if seg == "*" {
+			var values []docValue
+			for i, child := range node {
+				values = append(values, lookup(child, rest, joinPath(prefix, strconv.Itoa(i)))...)
+			}
+			return values
+		}
+		if idx, err := strconv.Atoi(seg); err == nil && idx >= 0 && idx < len(node) {
+			return lookup(node[idx], rest, joinPath(prefix, seg))
+		}
+	}
+
+	return nil
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
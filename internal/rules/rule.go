@@ -0,0 +1,217 @@
+// Package rules provides a diagnostics rules engine that detects known issues
+// in a bundle from declarative rules.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityError:
+		return "Error"
+	case SeverityCritical:
+		return "Critical"
+	}
+
+	return ""
+}
+
+func (s *Severity) UnmarshalText(b []byte) error {
+	switch strings.ToUpper(string(b)) {
+	case "INFO":
+		*s = SeverityInfo
+	case "WARNING":
+		*s = SeverityWarning
+	case "ERROR":
+		*s = SeverityError
+	case "CRITICAL":
+		*s = SeverityCritical
+	default:
+		return fmt.Errorf("unknown severity: %q", string(b))
+	}
+
+	return nil
+}
+
+// Condition matches a single value, found by field, in a log line or document.
+// Fields are dotted paths; in documents a "*" segment matches every element of
+// a list or map. A value matches if it satisfies every operator that is set.
+type Condition struct {
+	Field    string `yaml:"field"`
+	Equals   string `yaml:"equals,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
+	Regex    string `yaml:"regex,omitempty"`
+	// Exists, if set, requires the field to be present (true) or absent (false).
+	Exists *bool `yaml:"exists,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// LogMatcher matches lines of log files.
+type LogMatcher struct {
+	// Files is a glob pattern matched against the base name of log files. An
+	// empty pattern matches all files.
+	Files string `yaml:"files,omitempty"`
+	// Component restricts the matcher to logs of a component (e.g. "Agent").
+	Component  string      `yaml:"component,omitempty"`
+	Conditions []Condition `yaml:"conditions"`
+}
+
+// DocumentMatcher matches YAML or JSON files, such as configs or state files.
+type DocumentMatcher struct {
+	// Files is a glob pattern matched against the full path of files in the
+	// bundle. An empty pattern matches all files.
+	Files      string      `yaml:"files,omitempty"`
+	Conditions []Condition `yaml:"conditions"`
+}
+
+// Rule describes a known issue and how to detect it. A rule fires if any of
+// its matchers find evidence.
+type Rule struct {
+	ID          string            `yaml:"id"`
+	Name        string            `yaml:"name"`
+	Severity    Severity          `yaml:"severity"`
+	Description string            `yaml:"description"`
+	Remediation string            `yaml:"remediation"`
+	Logs        []LogMatcher      `yaml:"logs,omitempty"`
+	Configs     []DocumentMatcher `yaml:"configs,omitempty"`
+	Files       []DocumentMatcher `yaml:"files,omitempty"`
+}
+
+// compile validates the rule and prepares it for evaluation.
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return errors.New("rule has no id")
+	}
+	if len(r.Logs) == 0 && len(r.Configs) == 0 && len(r.Files) == 0 {
+		return fmt.Errorf("rule %q has no matchers", r.ID)
+	}
+
+	for i := range r.Logs {
+		if err := compileMatcher(r.Logs[i].Files, r.Logs[i].Conditions); err != nil {
+			return fmt.Errorf("rule %q: %w", r.ID, err)
+		}
+		if c := r.Logs[i].Component; c != "" && !isComponent(c) {
+			return fmt.Errorf("rule %q: unknown component %q", r.ID, c)
+		}
+	}
+	for _, matchers := range [][]DocumentMatcher{r.Configs, r.Files} {
+		for i := range matchers {
+			if err := compileMatcher(matchers[i].Files, matchers[i].Conditions); err != nil {
+				return fmt.Errorf("rule %q: %w", r.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func compileMatcher(files string, conditions []Condition) error {
+	if _, err := path.Match(files, ""); err != nil {
+		return fmt.Errorf("invalid files pattern %q: %w", files, err)
+	}
+	if len(conditions) == 0 {
+		return errors.New("matcher has no conditions")
+	}
+
+	for i := range conditions {
+		c := &conditions[i]
+		if c.Field == "" {
+			return errors.New("condition has no field")
+		}
+		if c.Regex != "" {
+			var err error
+			if c.regex, err = regexp.Compile(c.Regex); err != nil {
+				return fmt.Errorf("invalid regex for field %q: %w", c.Field, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isComponent(name string) bool {
+	for _, c := range []logs.Component{logs.ComponentGeneric, logs.ComponentAgent, logs.ComponentFilebeat, logs.ComponentMetricbeat} {
+		if strings.EqualFold(c.String(), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse reads rules from r. A file may contain several YAML documents, each
+// holding one rule.
+func Parse(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+
+	dec := yaml.NewDecoder(r)
+	for {
+		var rule Rule
+		if err := dec.Decode(&rule); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unable to decode rule: %w", err)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// LoadFS loads all rules from YAML files (*.yml, *.yaml) in dir of fsys.
+func LoadFS(fsys fs.FS, dir string) ([]*Rule, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules directory: %w", err)
+	}
+
+	var rules []*Rule
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		f, err := fsys.Open(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to open rules file %q: %w", entry.Name(), err)
+		}
+		fileRules, err := Parse(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load rules file %q: %w", entry.Name(), err)
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	return rules, nil
+}
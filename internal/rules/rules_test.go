@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/bundle"
+	_ "github.com/taylor-swanson/sawmill/internal/bundle/v1"
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	_ "github.com/taylor-swanson/sawmill/internal/component/logs/ndjson"
+)
+
+var testBundleFiles = map[string]string{
+	"meta/elastic-agent-version.yaml": "version: 8.6.0\n",
+	"config/elastic-agent-policy.yaml": `outputs:
+  default:
+    type: elasticsearch
+    ssl.verification_mode: none
+`,
+	"logs/elastic-agent-20230104.ndjson": `{"log.level":"info","message":"starting"}
+{"log.level":"error","message":"failed to checkin: connection refused"}
+{"log.level":"error","message":"x509: certificate signed by unknown authority"}
+`,
+	"state.yaml": `components:
+- id: log-default
+  state:
+    state: 2
+    units:
+      input-log-default:
+        state: 4
+        message: failed to start
+      output-log-default:
+        state: 2
+`,
+}
+
+func openTestBundle(t *testing.T) bundle.Viewer {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "bundle.zip")
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+
+	zw := zip.NewWriter(f)
+	for name, content := range testBundleFiles {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	viewer, err := bundle.NewViewer(filename)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = viewer.Close() })
+
+	return viewer
+}
+
+func testLogLoader(viewer bundle.Viewer) LogLoaderFunc {
+	return func(filename string) (*logs.Context, error) {
+		f, err := viewer.OpenFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		p, err := logs.NewParser("ndjson")
+		if err != nil {
+			return nil, err
+		}
+
+		return p.Parse(f)
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	rules, err := Builtin()
+	require.NoError(t, err)
+	require.NotEmpty(t, rules)
+
+	ids := map[string]bool{}
+	for _, r := range rules {
+		require.False(t, ids[r.ID], "duplicate rule id %q", r.ID)
+		ids[r.ID] = true
+		require.NotEmpty(t, r.Name, r.ID)
+		require.NotEmpty(t, r.Remediation, r.ID)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules, err := Builtin()
+	require.NoError(t, err)
+
+	viewer := openTestBundle(t)
+
+	findings, err := Evaluate(rules, viewer, testLogLoader(viewer))
+	require.NoError(t, err)
+
+	got := map[string]Finding{}
+	var order []string
+	for _, f := range findings {
+		got[f.Rule.ID] = f
+		order = append(order, f.Rule.ID)
+	}
+
+	require.Equal(t, []string{"unit-failed", "certificate-error", "fleet-checkin-failed", "output-ssl-verification-disabled"}, order)
+
+	require.Equal(t, []Evidence{{
+		Filename: "state.yaml",
+		Line:     -1,
+		Field:    "components.0.state.units.input-log-default.state",
+		Value:    "4",
+	}}, got["unit-failed"].Evidence)
+
+	require.Equal(t, 1, got["fleet-checkin-failed"].Total)
+	require.Equal(t, 1, got["fleet-checkin-failed"].Evidence[0].Line)
+	require.True(t, got["fleet-checkin-failed"].Evidence[0].IsLogLine())
+
+	require.Equal(t, "outputs.default.ssl.verification_mode", got["output-ssl-verification-disabled"].Evidence[0].Field)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]struct {
+		In      string
+		WantErr string
+	}{
+		"no_id": {
+			In:      "name: test\nlogs:\n  - conditions:\n      - field: message\n        contains: x\n",
+			WantErr: "rule has no id",
+		},
+		"no_matchers": {
+			In:      "id: test\n",
+			WantErr: "has no matchers",
+		},
+		"bad_regex": {
+			In:      "id: test\nlogs:\n  - conditions:\n      - field: message\n        regex: '('\n",
+			WantErr: "invalid regex",
+		},
+		"bad_severity": {
+			In:      "id: test\nseverity: meh\n",
+			WantErr: "unknown severity",
+		},
+		"bad_component": {
+			In:      "id: test\nlogs:\n  - component: nope\n    conditions:\n      - field: message\n        contains: x\n",
+			WantErr: "unknown component",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.In))
+			require.ErrorContains(t, err, tc.WantErr)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Collection groups the sessions of bundles collected together, such as from
+// many agents during an incident.
+type Collection struct {
+	ID   uuid.UUID
+	Name string
+
+	hashes []string
+	mu     sync.RWMutex
+}
+
+// NewCollection creates an empty collection.
+func NewCollection(name string) *Collection {
+	return &Collection{
+		ID:   uuid.New(),
+		Name: name,
+	}
+}
+
+// Add adds sessions to the collection by their bundle hash. Sessions already
+// in the collection are ignored.
+func (c *Collection) Add(hashes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+outer:
+	for _, hash := range hashes {
+		for _, existing := range c.hashes {
+			if existing == hash {
+				continue outer
+			}
+		}
+		c.hashes = append(c.hashes, hash)
+	}
+}
+
+// Hashes returns the bundle hashes of the sessions in the collection, in the
+// order they were added.
+func (c *Collection) Hashes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hashes := make([]string, len(c.hashes))
+	copy(hashes, c.hashes)
+
+	return hashes
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"container/list"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+// DefaultLogCacheSize is the default maximum size of a LogCache, in bytes. Each
+// session has its own cache, so this bounds the memory used per session, not
+// in total.
+const DefaultLogCacheSize = 1024 * 1024 * 1024 // 1 GB
+
+// LogCacheKey identifies a log file parsed with a parser.
+type LogCacheKey struct {
+	Filename string
+	Parser   string
+}
+
+func (k LogCacheKey) String() string {
+	return k.Parser + ":" + k.Filename
+}
+
+type logCacheEntry struct {
+	key    LogCacheKey
+	logCtx *logs.Context
+	size   int64
+}
+
+// LogCache caches parsed log contexts. When the estimated memory usage of the
+// cached contexts exceeds its maximum size, the least recently used contexts
+// are evicted. The most recently added context is always kept, even if it
+// exceeds the maximum size by itself. Sizes are estimated with
+// logs.Context.MemoryUsage.
+type LogCache struct {
+	maxSize int64
+	size    int64
+	entries map[LogCacheKey]*list.Element
+	lru     *list.List
+	mu      sync.Mutex
+
+	group singleflight.Group
+}
+
+// Get returns the cached context for key.
+func (c *LogCache) Get(key LogCacheKey) (*logs.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+
+	return elem.Value.(*logCacheEntry).logCtx, true
+}
+
+// Load returns the cached context for key, calling load to parse it if it is
+// not cached. Concurrent loads of the same key share a single call to load.
+func (c *LogCache) Load(key LogCacheKey, load func() (*logs.Context, error)) (*logs.Context, error) {
+	if logCtx, ok := c.Get(key); ok {
+		return logCtx, nil
+	}
+
+	v, err, _ := c.group.Do(key.String(), func() (any, error) {
+		// Another load may have finished between the lookup and this call.
+		if logCtx, ok := c.Get(key); ok {
+			return logCtx, nil
+		}
+
+		logCtx, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.Add(key, logCtx)
+
+		return logCtx, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*logs.Context), nil
+}
+
+// Add adds a context to the cache, evicting the least recently used contexts
+// if the cache is full.
+func (c *LogCache) Add(key LogCacheKey, logCtx *logs.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &logCacheEntry{key: key, logCtx: logCtx, size: logCtx.MemoryUsage()}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	for c.size > c.maxSize && c.lru.Len() > 1 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Len returns the number of cached contexts.
+func (c *LogCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Size returns the estimated memory usage of the cached contexts, in bytes.
+func (c *LogCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// remove removes an entry. The caller must hold the lock.
+func (c *LogCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*logCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// NewLogCache creates a LogCache holding up to maxSize bytes of contexts.
+func NewLogCache(maxSize int64) *LogCache {
+	return &LogCache{
+		maxSize: maxSize,
+		entries: map[LogCacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/taylor-swanson/sawmill/internal/component/logs"
+)
+
+func newTestLogContext(lines int) *logs.Context {
+	logCtx := logs.NewContext(logs.DefaultContextConfig())
+	for i := 0; i < lines; i++ {
+		logCtx.AddLineRaw(strings.Repeat("x", 100))
+	}
+	logCtx.Analyze()
+
+	return logCtx
+}
+
+func TestLogCache_Eviction(t *testing.T) {
+	size := newTestLogContext(10).MemoryUsage()
+	require.Greater(t, size, int64(0))
+
+	a := LogCacheKey{Filename: "a.ndjson", Parser: "ndjson"}
+	b := LogCacheKey{Filename: "b.ndjson", Parser: "ndjson"}
+	c := LogCacheKey{Filename: "c.ndjson", Parser: "ndjson"}
+
+	cache := NewLogCache(2 * size)
+	cache.Add(a, newTestLogContext(10))
+	cache.Add(b, newTestLogContext(10))
+	require.Equal(t, 2, cache.Len())
+	require.Equal(t, 2*size, cache.Size())
+
+	// Using a makes b the least recently used.
+	_, ok := cache.Get(a)
+	require.True(t, ok)
+	cache.Add(c, newTestLogContext(10))
+
+	_, ok = cache.Get(b)
+	require.False(t, ok)
+	_, ok = cache.Get(a)
+	require.True(t, ok)
+	_, ok = cache.Get(c)
+	require.True(t, ok)
+
+	// The same file with another parser is cached separately.
+	_, ok = cache.Get(LogCacheKey{Filename: "a.ndjson", Parser: "text"})
+	require.False(t, ok)
+
+	// A context larger than the cache is kept until another is added.
+	cache.Add(b, newTestLogContext(100))
+	require.Equal(t, 1, cache.Len())
+	_, ok = cache.Get(b)
+	require.True(t, ok)
+}
+
+func TestLogCache_Load(t *testing.T) {
+	key := LogCacheKey{Filename: "a.ndjson", Parser: "ndjson"}
+	cache := NewLogCache(DefaultLogCacheSize)
+
+	_, err := cache.Load(key, func() (*logs.Context, error) {
+		return nil, errors.New("parse failed")
+	})
+	require.EqualError(t, err, "parse failed")
+	require.Equal(t, 0, cache.Len())
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func() (*logs.Context, error) {
+		calls.Add(1)
+		<-release
+		return newTestLogContext(1), nil
+	}
+
+	const loaders = 10
+	results := make([]*logs.Context, loaders)
+	var wg sync.WaitGroup
+	var started sync.WaitGroup
+	for i := 0; i < loaders; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			logCtx, err := cache.Load(key, load)
+			require.NoError(t, err)
+			results[i] = logCtx
+		}(i)
+	}
+	started.Wait()
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, calls.Load())
+	for _, logCtx := range results {
+		require.Same(t, results[0], logCtx)
+	}
+
+	logCtx, err := cache.Load(key, load)
+	require.NoError(t, err)
+	require.Same(t, results[0], logCtx)
+	require.EqualValues(t, 1, calls.Load())
+}
@@ -1,10 +1,14 @@
 package session
 
 import (
+	"sync"
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/taylor-swanson/sawmill/internal/bundle"
 	"github.com/taylor-swanson/sawmill/internal/component/logs"
+	"github.com/taylor-swanson/sawmill/internal/jobs"
 )
 
 type Session struct {
@@ -13,5 +17,109 @@ type Session struct {
 	OriginalFilename string
 	Hash             string
 	Viewer           bundle.Viewer
-	LogContexts      map[string]*logs.Context
+	// Logs caches the parsed log files of the bundle.
+	Logs *LogCache
+
+	columns   map[logs.Component][]string
+	columnsMu sync.RWMutex
+
+	collected     time.Time
+	collectedOnce sync.Once
+
+	offsets   map[string]time.Duration
+	offsetsMu sync.RWMutex
+
+	findings   *jobs.Job
+	findingsMu sync.Mutex
+}
+
+// Findings returns the job evaluating the diagnostics rules against the bundle,
+// calling start to start it the first time, so the rules are only evaluated
+// once per session.
+func (s *Session) Findings(start func() *jobs.Job) *jobs.Job {
+	s.findingsMu.Lock()
+	defer s.findingsMu.Unlock()
+
+	if s.findings == nil {
+		s.findings = start()
+	}
+
+	return s.findings
+}
+
+// Offset returns the clock offset applied to the timestamps of a log file when
+// interleaving logs in a timeline.
+func (s *Session) Offset(filename string) time.Duration {
+	s.offsetsMu.RLock()
+	defer s.offsetsMu.RUnlock()
+
+	return s.offsets[filename]
+}
+
+// Offsets returns the clock offsets of all log files with an offset.
+func (s *Session) Offsets() map[string]time.Duration {
+	s.offsetsMu.RLock()
+	defer s.offsetsMu.RUnlock()
+
+	offsets := make(map[string]time.Duration, len(s.offsets))
+	for k, v := range s.offsets {
+		offsets[k] = v
+	}
+
+	return offsets
+}
+
+// SetOffset sets the clock offset of a log file. A zero offset removes it.
+func (s *Session) SetOffset(filename string, offset time.Duration) {
+	s.offsetsMu.Lock()
+	defer s.offsetsMu.Unlock()
+
+	if offset == 0 {
+		delete(s.offsets, filename)
+		return
+	}
+	if s.offsets == nil {
+		s.offsets = map[string]time.Duration{}
+	}
+	s.offsets[filename] = offset
+}
+
+// CollectionTime returns when the bundle was collected, which anchors relative
+// times such as "now-15m". If unknown, the current time is returned.
+func (s *Session) CollectionTime() time.Time {
+	s.collectedOnce.Do(func() {
+		s.collected = bundle.CollectionTime(s.Viewer)
+	})
+	if s.collected.IsZero() {
+		return time.Now()
+	}
+
+	return s.collected
+}
+
+// Columns returns the columns chosen for viewing logs of a component. If no
+// columns have been chosen, false is returned.
+func (s *Session) Columns(component logs.Component) ([]string, bool) {
+	s.columnsMu.RLock()
+	defer s.columnsMu.RUnlock()
+
+	columns, ok := s.columns[component]
+
+	return columns, ok
+}
+
+// SetColumns sets the columns for viewing logs of a component. Passing no
+// columns resets the component back to its default columns.
+func (s *Session) SetColumns(component logs.Component, columns []string) {
+	s.columnsMu.Lock()
+	defer s.columnsMu.Unlock()
+
+	if len(columns) == 0 {
+		delete(s.columns, component)
+		return
+	}
+	if s.columns == nil {
+		s.columns = map[logs.Component][]string{}
+	}
+	s.columns[component] = columns
 }
@@ -0,0 +1,283 @@
+// Package upload implements resumable uploads of large files. Files are sent
+// in chunks, which are written to the file's final location and hashed as they
+// arrive, so an interrupted upload can continue from the last byte received.
+package upload
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Retention is how long an upload which receives no data is kept before it is
+// removed.
+const Retention = 24 * time.Hour
+
+var (
+	ErrNotFound       = errors.New("upload not found")
+	ErrTooLarge       = errors.New("upload too large")
+	ErrEmpty          = errors.New("upload is empty")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	ErrLocked         = errors.New("upload in progress")
+	ErrClosed         = errors.New("upload manager closed")
+)
+
+// Upload is a file being uploaded.
+type Upload struct {
+	ID string
+	// Filename is the name of the file on the client.
+	Filename string
+	// Length is the size of the complete file.
+	Length int64
+
+	path    string
+	file    *os.File
+	hash    hash.Hash
+	offset  int64
+	updated time.Time
+	// writing is held while a chunk is written.
+	writing sync.Mutex
+	mu      sync.RWMutex
+}
+
+// Offset returns the number of bytes received.
+func (u *Upload) Offset() int64 {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.offset
+}
+
+// Done returns true if the whole file has been received.
+func (u *Upload) Done() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.offset == u.Length
+}
+
+// Path returns the location of the file.
+func (u *Upload) Path() string {
+	return u.path
+}
+
+// Hash returns the SHA-256 hash of the file, or an empty string until the
+// whole file has been received.
+func (u *Upload) Hash() string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if u.offset != u.Length {
+		return ""
+	}
+
+	return fmt.Sprintf("%x", u.hash.Sum(nil))
+}
+
+// Append writes the data read from r to the file, which must start at offset.
+// Data beyond the length of the file is an error. The offset reached is
+// returned even if r fails, so the upload can be resumed from there.
+func (u *Upload) Append(offset int64, r io.Reader) (int64, error) {
+	if !u.writing.TryLock() {
+		return u.Offset(), ErrLocked
+	}
+	defer u.writing.Unlock()
+
+	if current := u.Offset(); offset != current {
+		return current, fmt.Errorf("%w: got %d, expected %d", ErrOffsetMismatch, offset, current)
+	}
+
+	// One more byte than the remaining length is read to detect data beyond
+	// the end of the file.
+	_, err := io.Copy(writerFunc(u.write), io.LimitReader(r, u.Length-offset+1))
+
+	return u.Offset(), err
+}
+
+// write writes p to the file and the hash. Only the bytes written to the file
+// are hashed, keeping the two consistent if the write fails.
+func (u *Upload) write(p []byte) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if int64(len(p)) > u.Length-u.offset {
+		return 0, ErrTooLarge
+	}
+	if u.file == nil {
+		return 0, ErrClosed
+	}
+	n, err := u.file.Write(p)
+	u.hash.Write(p[:n])
+	u.offset += int64(n)
+	u.updated = time.Now()
+
+	return n, err
+}
+
+// close closes the file, removing it unless keep is true.
+func (u *Upload) close(keep bool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.file == nil {
+		return nil
+	}
+	err := u.file.Close()
+	u.file = nil
+	if !keep {
+		if rmErr := os.Remove(u.path); rmErr != nil {
+			err = rmErr
+		}
+	}
+
+	return err
+}
+
+func (u *Upload) updatedBefore(t time.Time) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.updated.Before(t)
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// Manager keeps track of uploads in progress.
+type Manager struct {
+	dir     string
+	pattern string
+	maxSize int64
+
+	uploads map[string]*Upload
+	closed  bool
+	mu      sync.Mutex
+}
+
+// MaxSize returns the largest file which can be uploaded, in bytes.
+func (m *Manager) MaxSize() int64 {
+	return m.maxSize
+}
+
+// Create starts an upload of a file of the given length.
+func (m *Manager) Create(filename string, length int64) (*Upload, error) {
+	if length <= 0 {
+		return nil, ErrEmpty
+	}
+	if length > m.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the maximum of %d bytes", ErrTooLarge, length, m.maxSize)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	m.prune()
+
+	file, err := os.CreateTemp(m.dir, m.pattern)
+	if err != nil {
+		return nil, err
+	}
+	u := &Upload{
+		ID:       uuid.NewString(),
+		Filename: filename,
+		Length:   length,
+		path:     file.Name(),
+		file:     file,
+		hash:     sha256.New(),
+		updated:  time.Now(),
+	}
+	m.uploads[u.ID] = u
+
+	return u, nil
+}
+
+// Get returns the upload with the given ID.
+func (m *Manager) Get(id string) (*Upload, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.uploads[id]
+
+	return u, ok
+}
+
+// Finish stops tracking a complete upload and closes its file, which is then
+// owned by the caller.
+func (m *Manager) Finish(id string) (*Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.uploads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !u.Done() {
+		return nil, fmt.Errorf("upload %q received %d of %d bytes", id, u.Offset(), u.Length)
+	}
+	delete(m.uploads, id)
+
+	return u, u.close(true)
+}
+
+// Remove cancels an upload and removes its file.
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.uploads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.uploads, id)
+
+	return u.close(false)
+}
+
+// Close cancels all uploads in progress, removing their files.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, u := range m.uploads {
+		_ = u.close(false)
+		delete(m.uploads, id)
+	}
+	m.closed = true
+}
+
+// prune removes uploads which received no data for longer than Retention. The
+// caller must hold the lock.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-Retention)
+	for id, u := range m.uploads {
+		if u.updatedBefore(cutoff) {
+			_ = u.close(false)
+			delete(m.uploads, id)
+		}
+	}
+}
+
+// NewManager creates a Manager writing files to dir, named using pattern as in
+// os.CreateTemp, and accepting files of at most maxSize bytes.
+func NewManager(dir, pattern string, maxSize int64) *Manager {
+	return &Manager{
+		dir:     dir,
+		pattern: pattern,
+		maxSize: maxSize,
+		uploads: map[string]*Upload{},
+	}
+}
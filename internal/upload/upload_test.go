@@ -0,0 +1,114 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload_Append(t *testing.T) {
+	const content = "hello, resumable world"
+
+	m := NewManager(t.TempDir(), "upload-*", 100)
+	defer m.Close()
+
+	u, err := m.Create("hello.txt", int64(len(content)))
+	require.NoError(t, err)
+	got, ok := m.Get(u.ID)
+	require.True(t, ok)
+	require.Same(t, u, got)
+
+	offset, err := u.Append(0, strings.NewReader(content[:5]))
+	require.NoError(t, err)
+	require.EqualValues(t, 5, offset)
+	require.False(t, u.Done())
+	require.Empty(t, u.Hash())
+
+	// A chunk must start where the last one ended.
+	offset, err = u.Append(3, strings.NewReader(content[3:]))
+	require.ErrorIs(t, err, ErrOffsetMismatch)
+	require.EqualValues(t, 5, offset)
+
+	// Bytes read before a failure are kept.
+	offset, err = u.Append(5, iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(content[5:]))))
+	require.ErrorIs(t, err, iotest.ErrTimeout)
+	require.EqualValues(t, 6, offset)
+
+	_, err = m.Finish(u.ID)
+	require.Error(t, err)
+
+	offset, err = u.Append(6, strings.NewReader(content[6:]))
+	require.NoError(t, err)
+	require.EqualValues(t, len(content), offset)
+	require.True(t, u.Done())
+	require.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte(content))), u.Hash())
+
+	_, err = u.Append(offset, strings.NewReader("more"))
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	finished, err := m.Finish(u.ID)
+	require.NoError(t, err)
+	require.Same(t, u, finished)
+	_, ok = m.Get(u.ID)
+	require.False(t, ok)
+
+	data, err := os.ReadFile(u.Path())
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
+
+func TestManager_Create(t *testing.T) {
+	tests := map[string]struct {
+		length  int64
+		wantErr error
+	}{
+		"ok":        {length: 10},
+		"max":       {length: 100},
+		"too-large": {length: 101, wantErr: ErrTooLarge},
+		"empty":     {length: 0, wantErr: ErrEmpty},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(t.TempDir(), "upload-*", 100)
+			defer m.Close()
+
+			u, err := m.Create(name, tc.length)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, name, u.Filename)
+			require.Equal(t, tc.length, u.Length)
+		})
+	}
+}
+
+func TestManager_Remove(t *testing.T) {
+	m := NewManager(t.TempDir(), "upload-*", 100)
+
+	a, err := m.Create("a", 10)
+	require.NoError(t, err)
+	b, err := m.Create("b", 10)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Remove(a.ID))
+	require.ErrorIs(t, m.Remove(a.ID), ErrNotFound)
+	_, err = os.Stat(a.Path())
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	// Closing removes the uploads in progress.
+	m.Close()
+	_, err = os.Stat(b.Path())
+	require.True(t, errors.Is(err, os.ErrNotExist))
+	_, err = m.Create("c", 10)
+	require.ErrorIs(t, err, ErrClosed)
+}